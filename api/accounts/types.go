@@ -8,6 +8,7 @@ package accounts
 import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/pkg/errors"
 	"github.com/vechain/thor/api/transactions"
 	"github.com/vechain/thor/runtime"
 	"github.com/vechain/thor/thor"
@@ -47,7 +48,8 @@ func convertVMOutputWithInputGas(vo *runtime.Output, inputGas uint64) *VMOutput
 
 	if vo.VMErr != nil {
 		reverted = true
-		vmError = vo.VMErr.Error()
+		// report the cause only, without location carried by vm.ExecError
+		vmError = errors.Cause(vo.VMErr).Error()
 	}
 
 	events := make([]*transactions.Event, len(vo.Events))
@@ -51,6 +51,16 @@ func (a *Authority) Get(signer thor.Address) (*Candidate, bool) {
 	}, true
 }
 
+// EndorsementOf returns balance of the endorsor of the given node master (signer).
+// Zero returned if the node is not listed.
+func (a *Authority) EndorsementOf(nodeMaster thor.Address) *big.Int {
+	candidate, ok := a.Get(nodeMaster)
+	if !ok {
+		return &big.Int{}
+	}
+	return a.state.GetBalance(candidate.Endorsor)
+}
+
 func (a *Authority) getAndSet(signer thor.Address, f func(entry *entry) bool) bool {
 	key := thor.BytesToBytes32(signer[:])
 	var entry entry
@@ -166,3 +176,22 @@ func (a *Authority) Next(signer thor.Address) *thor.Address {
 	a.getStorage(thor.BytesToBytes32(signer[:]), &entry)
 	return entry.Next
 }
+
+// Count returns the number of listed candidates.
+func (a *Authority) Count() uint64 {
+	var count uint64
+	for ptr := a.First(); ptr != nil; ptr = a.Next(*ptr) {
+		count++
+	}
+	return count
+}
+
+// IsEndorsor returns whether addr is the endorsor of any listed candidate.
+func (a *Authority) IsEndorsor(addr thor.Address) bool {
+	for ptr := a.First(); ptr != nil; ptr = a.Next(*ptr) {
+		if candidate, ok := a.Get(*ptr); ok && candidate.Endorsor == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -65,3 +65,81 @@ func TestAuthority(t *testing.T) {
 	}
 
 }
+
+func TestEndorsementOf(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	master := thor.BytesToAddress([]byte("master"))
+	endorsor := thor.BytesToAddress([]byte("endorsor"))
+	st.SetBalance(endorsor, big.NewInt(100))
+
+	aut := New(thor.BytesToAddress([]byte("aut")), st)
+	aut.Add(&Candidate{master, endorsor, thor.Bytes32{}, true})
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{aut.EndorsementOf(master), big.NewInt(100)},
+		{aut.EndorsementOf(thor.BytesToAddress([]byte("unlisted"))), &big.Int{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestCount(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	p1 := thor.BytesToAddress([]byte("p1"))
+	p2 := thor.BytesToAddress([]byte("p2"))
+	p3 := thor.BytesToAddress([]byte("p3"))
+
+	aut := New(thor.BytesToAddress([]byte("aut")), st)
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{aut.Count(), uint64(0)},
+		{aut.Add(&Candidate{p1, p1, thor.Bytes32{}, true}), true},
+		{aut.Add(&Candidate{p2, p2, thor.Bytes32{}, true}), true},
+		{aut.Add(&Candidate{p3, p3, thor.Bytes32{}, false}), true},
+		{aut.Count(), uint64(3)},
+		{aut.Remove(p2), true},
+		{aut.Count(), uint64(2)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestIsEndorsor(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	p1 := thor.BytesToAddress([]byte("p1"))
+	p2 := thor.BytesToAddress([]byte("p2"))
+	e1 := thor.BytesToAddress([]byte("e1"))
+	e2 := thor.BytesToAddress([]byte("e2"))
+
+	aut := New(thor.BytesToAddress([]byte("aut")), st)
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{aut.IsEndorsor(e1), false},
+		{aut.Add(&Candidate{p1, e1, thor.Bytes32{}, true}), true},
+		{aut.Add(&Candidate{p2, e2, thor.Bytes32{}, false}), true},
+		{aut.IsEndorsor(e1), true},
+		{aut.IsEndorsor(e2), true},
+		{aut.IsEndorsor(p1), false},
+		{aut.IsEndorsor(thor.BytesToAddress([]byte("unrelated"))), false},
+		{aut.Remove(p1), true},
+		{aut.IsEndorsor(e1), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
@@ -8,6 +8,7 @@ package energy
 import (
 	"math/big"
 
+	"github.com/vechain/thor/builtin/params"
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
 )
@@ -15,6 +16,7 @@ import (
 var (
 	initialSupplyKey = thor.Blake2b([]byte("initial-supply"))
 	totalAddSubKey   = thor.Blake2b([]byte("total-add-sub"))
+	burnedKey        = thor.Blake2b([]byte("burned"))
 )
 
 // Energy implements energy operations.
@@ -22,11 +24,28 @@ type Energy struct {
 	addr      thor.Address
 	state     *state.State
 	blockTime uint64
+	blockNum  uint64
+	params    *params.Params
 }
 
 // New creates a new energy instance.
 func New(addr thor.Address, state *state.State, blockTime uint64) *Energy {
-	return &Energy{addr, state, blockTime}
+	return &Energy{addr: addr, state: state, blockTime: blockTime}
+}
+
+// WithBlockNumber sets number of the block at block time, which is the reference of BalanceAtBlock.
+// It's zero if not set.
+// Returns this energy instance.
+func (e *Energy) WithBlockNumber(blockNum uint64) *Energy {
+	e.blockNum = blockNum
+	return e
+}
+
+// WithParams sets the params where GrowthRate is read from.
+// Returns this energy instance.
+func (e *Energy) WithParams(params *params.Params) *Energy {
+	e.params = params
+	return e
 }
 
 func (e *Energy) getStorage(key thor.Bytes32, val interface{}) {
@@ -55,17 +74,17 @@ func (e *Energy) TokenTotalSupply() *big.Int {
 	return init.Token
 }
 
-// TotalSupply returns total supply of energy.
+// TotalSupply returns total supply of energy, excluding the burned by Burn.
 func (e *Energy) TotalSupply() *big.Int {
 	var init initialSupply
 	e.getStorage(initialSupplyKey, &init)
 
 	// calc grown energy for total token supply
 	acc := state.Account{Balance: init.Token, Energy: init.Energy, BlockTime: init.BlockTime}
-	return acc.CalcEnergy(e.blockTime)
+	return new(big.Int).Sub(acc.CalcEnergy(e.blockTime), e.Burned())
 }
 
-// TotalBurned returns energy totally burned.
+// TotalBurned returns energy totally burned, that's energy consumed by Sub minus the added by Add.
 func (e *Energy) TotalBurned() *big.Int {
 	var total totalAddSub
 	e.getStorage(totalAddSubKey, &total)
@@ -77,6 +96,48 @@ func (e *Energy) Get(addr thor.Address) *big.Int {
 	return e.state.GetEnergy(addr, e.blockTime)
 }
 
+// GrowthRate returns energy growth rate, in wei per VET per block.
+// It's read from params as thor.KeyEnergyGrowthRate, and defaults to thor.EnergyGrowthRate,
+// the per second rate, times thor.BlockInterval if unset.
+func (e *Energy) GrowthRate() *big.Int {
+	if e.params != nil {
+		if rate := e.params.Get(thor.KeyEnergyGrowthRate); rate.Sign() != 0 {
+			return rate
+		}
+	}
+	return new(big.Int).Mul(thor.EnergyGrowthRate, new(big.Int).SetUint64(thor.BlockInterval))
+}
+
+// BalanceAtBlock returns energy of an account projected to the block at given number,
+// growing at GrowthRate from the block of the last update of the account.
+// Block numbers are timed by thor.BlockInterval relative to the block set by WithBlockNumber.
+// The stored energy is returned if the block is not after the last update.
+func (e *Energy) BalanceAtBlock(addr thor.Address, blockNum uint64) *big.Int {
+	// energy as last updated, without growth
+	stored := e.state.GetEnergy(addr, 0)
+
+	// block number of the last update
+	var lastNum uint64
+	if updated := e.state.GetEnergyBlockTime(addr); updated >= e.blockTime {
+		lastNum = e.blockNum + (updated-e.blockTime)/thor.BlockInterval
+		if lastNum < e.blockNum {
+			// overflowed, too far to grow
+			return stored
+		}
+	} else if elapsed := (e.blockTime - updated) / thor.BlockInterval; elapsed < e.blockNum {
+		lastNum = e.blockNum - elapsed
+	}
+	if blockNum <= lastNum {
+		return stored
+	}
+
+	x := new(big.Int).SetUint64(blockNum - lastNum)
+	x.Mul(x, e.state.GetBalance(addr))
+	x.Mul(x, e.GrowthRate())
+	x.Div(x, big.NewInt(1e18))
+	return x.Add(x, stored)
+}
+
 // Add add amount of energy to given address.
 func (e *Energy) Add(addr thor.Address, amount *big.Int) {
 	eng := e.state.GetEnergy(addr, e.blockTime)
@@ -116,3 +177,28 @@ func (e *Energy) Sub(addr thor.Address, amount *big.Int) bool {
 	}
 	return true
 }
+
+// Burned returns energy burned by Burn.
+func (e *Energy) Burned() *big.Int {
+	var burned big.Int
+	e.getStorage(burnedKey, &burned)
+	return &burned
+}
+
+// Burn burns amount of energy from given address, which is removed from total supply
+// and accounted in Burned.
+// False is returned if amount is negative or no enough energy.
+func (e *Energy) Burn(addr thor.Address, amount *big.Int) bool {
+	if amount.Sign() < 0 {
+		return false
+	}
+	eng := e.state.GetEnergy(addr, e.blockTime)
+	if eng.Cmp(amount) < 0 {
+		return false
+	}
+	if amount.Sign() != 0 {
+		e.setStorage(burnedKey, new(big.Int).Add(e.Burned(), amount))
+	}
+	e.state.SetEnergy(addr, new(big.Int).Sub(eng, amount), e.blockTime)
+	return true
+}
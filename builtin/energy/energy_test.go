@@ -6,10 +6,12 @@
 package energy
 
 import (
+	"math"
 	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vechain/thor/builtin/params"
 	"github.com/vechain/thor/lvldb"
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
@@ -38,6 +40,42 @@ func TestEnergy(t *testing.T) {
 	}
 }
 
+func TestEnergyBurn(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	acc := thor.BytesToAddress([]byte("a1"))
+
+	eng := New(thor.BytesToAddress([]byte("eng")), st, 0)
+	eng.SetInitialSupply(&big.Int{}, big.NewInt(100))
+	st.SetEnergy(acc, big.NewInt(10), 0)
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{eng.Burn(acc, big.NewInt(4)), true},
+		{eng.Get(acc), big.NewInt(6)},
+		{eng.Burned(), big.NewInt(4)},
+		{eng.TotalSupply(), big.NewInt(96)},
+		// not a debit
+		{eng.TotalBurned().Sign(), 0},
+		{eng.Burn(acc, big.NewInt(7)), false},
+		{eng.Get(acc), big.NewInt(6)},
+		{eng.Burned(), big.NewInt(4)},
+		{eng.TotalSupply(), big.NewInt(96)},
+		// no minting
+		{eng.Burn(acc, big.NewInt(-1)), false},
+		{eng.Get(acc), big.NewInt(6)},
+		{eng.Burned(), big.NewInt(4)},
+		{eng.TotalSupply(), big.NewInt(96)},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
 func TestEnergyGrowth(t *testing.T) {
 	kv, _ := lvldb.NewMem()
 	st, _ := state.New(thor.Bytes32{}, kv)
@@ -60,3 +98,74 @@ func TestEnergyGrowth(t *testing.T) {
 	assert.Equal(t, x, bal1)
 
 }
+
+func TestEnergyBalanceAtBlock(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	acc := thor.BytesToAddress([]byte("a1"))
+	vetBal := big.NewInt(1e18)
+	st.SetBalance(acc, vetBal)
+	// last updated at block 50, 50 blocks before block 100
+	st.SetEnergy(acc, big.NewInt(10), 500)
+
+	eng := New(thor.BytesToAddress([]byte("eng")), st, 1000).WithBlockNumber(100)
+
+	grown := func(blocks uint64) *big.Int {
+		x := new(big.Int).Mul(eng.GrowthRate(), vetBal)
+		x.Mul(x, new(big.Int).SetUint64(blocks))
+		x.Div(x, big.NewInt(1e18))
+		return x.Add(x, big.NewInt(10))
+	}
+
+	tests := []struct {
+		blockNum uint64
+		expected *big.Int
+	}{
+		{100, grown(50)},
+		{110, grown(60)},
+		{1100, grown(1050)},
+		{math.MaxUint64, grown(math.MaxUint64 - 50)},
+		// not after the last update
+		{50, big.NewInt(10)},
+		{40, big.NewInt(10)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, eng.BalanceAtBlock(acc, tt.blockNum))
+	}
+
+	// agrees with energy actually grown
+	assert.Equal(t, st.GetEnergy(acc, 1100), eng.BalanceAtBlock(acc, 110))
+
+	// relative to block 0 if block number not set
+	eng = New(thor.BytesToAddress([]byte("eng")), st, 1000)
+	assert.Equal(t, big.NewInt(10), eng.BalanceAtBlock(acc, 0))
+	assert.Equal(t, grown(5), eng.BalanceAtBlock(acc, 5))
+}
+
+func TestEnergyGrowthRate(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	acc := thor.BytesToAddress([]byte("a1"))
+	vetBal := big.NewInt(1e18)
+	st.SetBalance(acc, vetBal)
+
+	p := params.New(thor.BytesToAddress([]byte("par")), st)
+	eng := New(thor.BytesToAddress([]byte("eng")), st, 1000).WithBlockNumber(100).WithParams(p)
+	eng.Add(acc, big.NewInt(10))
+
+	// default rate if not set in params
+	rate := new(big.Int).Mul(thor.EnergyGrowthRate, new(big.Int).SetUint64(thor.BlockInterval))
+	assert.Equal(t, rate, eng.GrowthRate())
+	assert.Equal(t, st.GetEnergy(acc, 1000+10*thor.BlockInterval), eng.BalanceAtBlock(acc, 110))
+
+	rate = big.NewInt(1e10)
+	p.Set(thor.KeyEnergyGrowthRate, rate)
+	assert.Equal(t, rate, eng.GrowthRate())
+
+	x := new(big.Int).Mul(rate, vetBal)
+	x.Mul(x, big.NewInt(10))
+	x.Div(x, big.NewInt(1e18))
+	assert.Equal(t, x.Add(x, big.NewInt(10)), eng.BalanceAtBlock(acc, 110))
+}
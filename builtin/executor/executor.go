@@ -18,3 +18,71 @@ type Executor struct {
 func New(addr thor.Address, state *state.State) *Executor {
 	return &Executor{addr, state}
 }
+
+func (e *Executor) proposalKey(id thor.Bytes32) thor.Bytes32 {
+	return thor.Blake2b(id.Bytes(), []byte("proposal"))
+}
+
+func (e *Executor) approvalKey(id thor.Bytes32, approver thor.Address) thor.Bytes32 {
+	return thor.Blake2b(id.Bytes(), approver.Bytes(), []byte("approval"))
+}
+
+func (e *Executor) getStorage(key thor.Bytes32, val interface{}) {
+	e.state.GetStructuredStorage(e.addr, key, val)
+}
+
+func (e *Executor) setStorage(key thor.Bytes32, val interface{}) {
+	e.state.SetStructuredStorage(e.addr, key, val)
+}
+
+// Propose creates a proposal, which requires quorum approvals to be executed.
+// False returned if the proposal exists or quorum is zero.
+func (e *Executor) Propose(id thor.Bytes32, quorum uint8) bool {
+	var p proposal
+	e.getStorage(e.proposalKey(id), &p)
+	if !p.IsEmpty() || quorum == 0 {
+		return false
+	}
+	e.setStorage(e.proposalKey(id), &proposal{Quorum: quorum})
+	return true
+}
+
+// Approve approves a proposal by the approver.
+// False returned if the proposal doesn't exist, is executed, or is already approved by the approver.
+func (e *Executor) Approve(id thor.Bytes32, approver thor.Address) bool {
+	var p proposal
+	e.getStorage(e.proposalKey(id), &p)
+	if p.IsEmpty() || p.Executed {
+		return false
+	}
+	var approved bool
+	e.getStorage(e.approvalKey(id, approver), &approved)
+	if approved {
+		return false
+	}
+	e.setStorage(e.approvalKey(id, approver), true)
+	p.Approvals++
+	e.setStorage(e.proposalKey(id), &p)
+	return true
+}
+
+// Execute marks a proposal executed.
+// False returned if the proposal hasn't reached quorum, or is already executed.
+func (e *Executor) Execute(id thor.Bytes32) bool {
+	var p proposal
+	e.getStorage(e.proposalKey(id), &p)
+	if p.IsEmpty() || p.Executed || p.Approvals < p.Quorum {
+		return false
+	}
+	p.Executed = true
+	e.setStorage(e.proposalKey(id), &p)
+	return true
+}
+
+// ProposalStatus returns approval count, quorum and whether executed of a proposal.
+// All zero values returned if the proposal doesn't exist.
+func (e *Executor) ProposalStatus(id thor.Bytes32) (approvals int, quorum int, executed bool) {
+	var p proposal
+	e.getStorage(e.proposalKey(id), &p)
+	return int(p.Approvals), int(p.Quorum), p.Executed
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+)
+
+func M(a ...interface{}) []interface{} {
+	return a
+}
+
+func TestProposalStatus(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	exe := New(thor.BytesToAddress([]byte("exe")), st)
+	id := thor.BytesToBytes32([]byte("proposal"))
+	a1 := thor.BytesToAddress([]byte("a1"))
+	a2 := thor.BytesToAddress([]byte("a2"))
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{M(exe.ProposalStatus(id)), M(0, 0, false)},
+		{exe.Propose(id, 2), true},
+		{exe.Propose(id, 2), false},
+		{M(exe.ProposalStatus(id)), M(0, 2, false)},
+		{exe.Approve(id, a1), true},
+		{exe.Approve(id, a1), false},
+		{M(exe.ProposalStatus(id)), M(1, 2, false)},
+		{exe.Execute(id), false},
+		{exe.Approve(id, a2), true},
+		{M(exe.ProposalStatus(id)), M(2, 2, false)},
+		{exe.Execute(id), true},
+		{M(exe.ProposalStatus(id)), M(2, 2, true)},
+		{exe.Execute(id), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+	assert.Nil(t, st.Err())
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package executor
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/vechain/thor/state"
+)
+
+type proposal struct {
+	Quorum    uint8
+	Approvals uint8
+	Executed  bool
+}
+
+var (
+	_ state.StorageEncoder = (*proposal)(nil)
+	_ state.StorageDecoder = (*proposal)(nil)
+)
+
+func (p *proposal) Encode() ([]byte, error) {
+	if p.IsEmpty() {
+		return nil, nil
+	}
+	return rlp.EncodeToBytes(p)
+}
+
+func (p *proposal) Decode(data []byte) error {
+	if len(data) == 0 {
+		*p = proposal{}
+		return nil
+	}
+	return rlp.DecodeBytes(data, p)
+}
+
+func (p *proposal) IsEmpty() bool {
+	return p.Quorum == 0
+}
@@ -6,17 +6,94 @@
 package extension
 
 import (
+	"errors"
+	"math/big"
+
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
 )
 
+var (
+	// ErrReentrancy returned by SafeTransfer if called within another SafeTransfer.
+	ErrReentrancy = errors.New("reentrant transfer")
+	// ErrInsufficientBalance returned by SafeTransfer if no enough balance to transfer.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// SignerResolver resolves signer of the block at the given number.
+type SignerResolver func(num uint32) thor.Address
+
+// TransferHook is called by SafeTransfer after VET transferred, e.g. to notify the recipient.
+type TransferHook func(from, to thor.Address, amount *big.Int)
+
 // Extension implements native methods of `Extension` contract.
 type Extension struct {
-	addr  thor.Address
-	state *state.State
+	addr          thor.Address
+	state         *state.State
+	resolveSigner SignerResolver
+	onTransfer    TransferHook
+	transferring  bool // set while SafeTransfer in progress
 }
 
 // New create a new instance.
 func New(addr thor.Address, state *state.State) *Extension {
-	return &Extension{addr, state}
+	return &Extension{addr: addr, state: state}
+}
+
+// WithSignerResolver sets the resolver used by BlockSigner.
+// Returns this extension.
+func (e *Extension) WithSignerResolver(resolver SignerResolver) *Extension {
+	e.resolveSigner = resolver
+	return e
+}
+
+// WithTransferHook sets the hook called by SafeTransfer.
+// Returns this extension.
+func (e *Extension) WithTransferHook(hook TransferHook) *Extension {
+	e.onTransfer = hook
+	return e
+}
+
+// BlockSigner returns signer of the block at the given number.
+// Zero address returned if no resolver set.
+func (e *Extension) BlockSigner(num uint32) thor.Address {
+	if e.resolveSigner == nil {
+		return thor.Address{}
+	}
+	return e.resolveSigner(num)
+}
+
+// StorageFor returns value of the storage slot of the target account.
+func (e *Extension) StorageFor(target thor.Address, slot thor.Bytes32) thor.Bytes32 {
+	return e.state.GetStorage(target, slot)
+}
+
+// SafeTransfer transfers amount of VET from one account to another, then calls the transfer hook if set.
+// SafeTransfer nested in the hook fails with ErrReentrancy.
+func (e *Extension) SafeTransfer(from, to thor.Address, amount *big.Int) error {
+	if e.transferring {
+		return ErrReentrancy
+	}
+	e.transferring = true
+	defer func() { e.transferring = false }()
+
+	balance := e.state.GetBalance(from)
+	if balance.Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+	e.state.SetBalance(from, new(big.Int).Sub(balance, amount))
+	e.state.SetBalance(to, new(big.Int).Add(e.state.GetBalance(to), amount))
+	if e.onTransfer != nil {
+		e.onTransfer(from, to, amount)
+	}
+	return nil
+}
+
+// BumpCounter returns the counter of self, and increases it by one.
+func (e *Extension) BumpCounter(self thor.Address) uint64 {
+	key := thor.Blake2b(self.Bytes(), []byte("counter"))
+	var counter uint64
+	e.state.GetStructuredStorage(e.addr, key, &counter)
+	e.state.SetStructuredStorage(e.addr, key, counter+1)
+	return counter
 }
@@ -0,0 +1,135 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package extension
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+)
+
+func TestBlockSigner(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	signers := []thor.Address{
+		thor.BytesToAddress([]byte("signer0")),
+		thor.BytesToAddress([]byte("signer1")),
+		thor.BytesToAddress([]byte("signer2")),
+	}
+	current := uint32(len(signers) - 1)
+
+	ext := New(thor.BytesToAddress([]byte("ext")), st)
+	assert.Equal(t, thor.Address{}, ext.BlockSigner(current))
+
+	ext.WithSignerResolver(func(num uint32) thor.Address {
+		if num > current {
+			return thor.Address{}
+		}
+		return signers[num]
+	})
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{ext.BlockSigner(current), signers[2]},
+		{ext.BlockSigner(1), signers[1]},
+		{ext.BlockSigner(0), signers[0]},
+		{ext.BlockSigner(current + 1), thor.Address{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestStorageFor(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	target := thor.BytesToAddress([]byte("target"))
+	slot := thor.BytesToBytes32([]byte("slot"))
+	value := thor.BytesToBytes32([]byte("value"))
+	st.SetStorage(target, slot, value)
+
+	ext := New(thor.BytesToAddress([]byte("ext")), st)
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{ext.StorageFor(target, slot), value},
+		{ext.StorageFor(target, thor.BytesToBytes32([]byte("other"))), thor.Bytes32{}},
+		{ext.StorageFor(thor.BytesToAddress([]byte("ext")), slot), thor.Bytes32{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestSafeTransfer(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	ext := New(thor.BytesToAddress([]byte("ext")), st)
+	from := thor.BytesToAddress([]byte("from"))
+	to := thor.BytesToAddress([]byte("to"))
+	st.SetBalance(from, big.NewInt(100))
+
+	// the recipient transfers back when notified
+	var reentered error
+	ext.WithTransferHook(func(from, to thor.Address, amount *big.Int) {
+		reentered = ext.SafeTransfer(to, from, amount)
+	})
+
+	assert.Nil(t, ext.SafeTransfer(from, to, big.NewInt(10)))
+	assert.Equal(t, ErrReentrancy, reentered)
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{st.GetBalance(from), big.NewInt(90)},
+		{st.GetBalance(to), big.NewInt(10)},
+		// guard is released
+		{ext.SafeTransfer(from, to, big.NewInt(90)), nil},
+		{ext.SafeTransfer(from, to, big.NewInt(1)), ErrInsufficientBalance},
+		{st.GetBalance(to), big.NewInt(100)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestBumpCounter(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	ext := New(thor.BytesToAddress([]byte("ext")), st)
+	self := thor.BytesToAddress([]byte("self"))
+	other := thor.BytesToAddress([]byte("other"))
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{ext.BumpCounter(self), uint64(0)},
+		{ext.BumpCounter(self), uint64(1)},
+		{ext.BumpCounter(self), uint64(2)},
+		// counters are per contract
+		{ext.BumpCounter(other), uint64(0)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+	assert.Nil(t, st.Err())
+}
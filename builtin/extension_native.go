@@ -64,17 +64,20 @@ func init() {
 			var blockNum uint32
 			env.ParseArgs(&blockNum)
 			env.Must(blockNum <= env.BlockContext().Number)
-			if blockNum == env.BlockContext().Number {
-				return []interface{}{env.BlockContext().Signer}
-			}
 
-			env.UseGas(thor.SloadGas)
-			id := env.Seeker().GetID(blockNum)
+			output := Extension.Native(env.State()).WithSignerResolver(func(num uint32) thor.Address {
+				if num == env.BlockContext().Number {
+					return env.BlockContext().Signer
+				}
+				env.UseGas(thor.SloadGas)
+				id := env.Seeker().GetID(num)
 
-			env.UseGas(thor.SloadGas)
-			header := env.Seeker().GetHeader(id)
-			signer, _ := header.Signer()
-			return []interface{}{signer}
+				env.UseGas(thor.SloadGas)
+				header := env.Seeker().GetHeader(id)
+				signer, _ := header.Signer()
+				return signer
+			}).BlockSigner(blockNum)
+			return []interface{}{output}
 		}},
 		{"native_totalSupply", func(env *xenv.Environment) []interface{} {
 			env.UseGas(thor.SloadGas)
@@ -6,12 +6,16 @@
 package params
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
 )
 
+// ErrUnauthorized returned by SetBaseGasPrice if the caller is not the executor.
+var ErrUnauthorized = errors.New("unauthorized")
+
 // Params binder of `Params` contract.
 type Params struct {
 	addr  thor.Address
@@ -29,6 +33,22 @@ func (p *Params) Get(key thor.Bytes32) *big.Int {
 	return &v
 }
 
+// BaseGasPrice returns the base gas price, stored as thor.KeyBaseGasPrice.
+func (p *Params) BaseGasPrice() *big.Int {
+	return p.Get(thor.KeyBaseGasPrice)
+}
+
+// SetBaseGasPrice sets the base gas price on behalf of caller, which must be the executor,
+// stored as thor.KeyExecutorAddress.
+func (p *Params) SetBaseGasPrice(caller thor.Address, value *big.Int) error {
+	executor := thor.BytesToAddress(p.Get(thor.KeyExecutorAddress).Bytes())
+	if caller != executor {
+		return ErrUnauthorized
+	}
+	p.Set(thor.KeyBaseGasPrice, value)
+	return nil
+}
+
 // Set native way to set param.
 func (p *Params) Set(key thor.Bytes32, value *big.Int) {
 	p.state.SetStructuredStorage(p.addr, key, value)
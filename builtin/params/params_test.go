@@ -26,3 +26,28 @@ func TestParamsGetSet(t *testing.T) {
 	getv := p.Get(key)
 	assert.Equal(t, setv, getv)
 }
+
+func TestBaseGasPrice(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	p := New(thor.BytesToAddress([]byte("par")), st)
+
+	assert.Equal(t, &big.Int{}, p.BaseGasPrice())
+	p.Set(thor.KeyBaseGasPrice, big.NewInt(1000))
+	assert.Equal(t, big.NewInt(1000), p.BaseGasPrice())
+}
+
+func TestSetBaseGasPrice(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	p := New(thor.BytesToAddress([]byte("par")), st)
+
+	executor := thor.BytesToAddress([]byte("executor"))
+	p.Set(thor.KeyExecutorAddress, new(big.Int).SetBytes(executor[:]))
+
+	assert.Nil(t, p.SetBaseGasPrice(executor, big.NewInt(1000)))
+	assert.Equal(t, big.NewInt(1000), p.BaseGasPrice())
+
+	assert.Equal(t, ErrUnauthorized, p.SetBaseGasPrice(thor.BytesToAddress([]byte("other")), big.NewInt(2000)))
+	assert.Equal(t, big.NewInt(1000), p.BaseGasPrice())
+}
@@ -25,6 +25,60 @@ func (p *Prototype) Bind(self thor.Address) *Binding {
 	return &Binding{p, self}
 }
 
+// SetMasterIfUnset sets master of self only if it has no master yet.
+// It returns whether the master is changed.
+func (p *Prototype) SetMasterIfUnset(self, master thor.Address) bool {
+	if !p.state.GetMaster(self).IsZero() {
+		return false
+	}
+	p.state.SetMaster(self, master)
+	return true
+}
+
+// AddUser adds user to the users of self.
+// It returns false if user is already a user of self.
+func (p *Prototype) AddUser(self, user thor.Address) bool {
+	binding := p.Bind(self)
+	if binding.IsUser(user) {
+		return false
+	}
+	// block time is not used until credit is consumed, which updates it.
+	// it's set non-zero only to mark the user existing.
+	binding.AddUser(user, 1)
+	return true
+}
+
+// IsUser returns whether user is a user of self.
+func (p *Prototype) IsUser(self, user thor.Address) bool {
+	return p.Bind(self).IsUser(user)
+}
+
+// CreditPlan returns the credit and recovery rate of users of self.
+func (p *Prototype) CreditPlan(self thor.Address) (credit *big.Int, recoveryRate *big.Int) {
+	return p.Bind(self).UserPlan()
+}
+
+// SetCreditPlan sets the credit and recovery rate of users of self.
+func (p *Prototype) SetCreditPlan(self thor.Address, credit, recoveryRate *big.Int) {
+	p.Bind(self).SetUserPlan(credit, recoveryRate)
+}
+
+func (p *Prototype) pausedKey(self thor.Address) thor.Bytes32 {
+	return thor.Blake2b(self.Bytes(), []byte("paused"))
+}
+
+// SetPaused sets whether calls to self are paused.
+func (p *Prototype) SetPaused(self thor.Address, paused bool) {
+	p.state.SetStructuredStorage(p.addr, p.pausedKey(self), paused)
+}
+
+// IsPaused returns whether calls to self are paused.
+func (p *Prototype) IsPaused(self thor.Address) bool {
+	var paused bool
+	p.state.GetStructuredStorage(p.addr, p.pausedKey(self), &paused)
+	return paused
+}
+
 type Binding struct {
 	prototype *Prototype
 	self      thor.Address
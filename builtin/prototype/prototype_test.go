@@ -73,3 +73,92 @@ func TestPrototype(t *testing.T) {
 
 	assert.Nil(t, st.Err())
 }
+
+func TestSetMasterIfUnset(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	proto := prototype.New(thor.BytesToAddress([]byte("proto")), st)
+	self := thor.BytesToAddress([]byte("self"))
+	master1 := thor.BytesToAddress([]byte("master1"))
+	master2 := thor.BytesToAddress([]byte("master2"))
+
+	tests := []struct {
+		fn       func() interface{}
+		expected interface{}
+		msg      string
+	}{
+		{func() interface{} { return proto.SetMasterIfUnset(self, master1) }, true, "should set on unset"},
+		{func() interface{} { return st.GetMaster(self) }, master1, "should be master1"},
+		{func() interface{} { return proto.SetMasterIfUnset(self, master2) }, false, "should not set on already set"},
+		{func() interface{} { return st.GetMaster(self) }, master1, "should be unchanged"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.fn(), tt.msg)
+	}
+
+	assert.Nil(t, st.Err())
+}
+
+func TestAddUser(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	proto := prototype.New(thor.BytesToAddress([]byte("proto")), st)
+	self := thor.BytesToAddress([]byte("self"))
+	user := thor.BytesToAddress([]byte("user"))
+
+	tests := []struct {
+		fn       func() interface{}
+		expected interface{}
+		msg      string
+	}{
+		{func() interface{} { return proto.IsUser(self, user) }, false, "should not be user"},
+		{func() interface{} { return proto.AddUser(self, user) }, true, "should add new user"},
+		{func() interface{} { return proto.IsUser(self, user) }, true, "should be user"},
+		{func() interface{} { return proto.AddUser(self, user) }, false, "should not add existing user"},
+		{func() interface{} { return proto.Bind(self).IsUser(user) }, true, "should be user of binding"},
+		{func() interface{} { return proto.IsUser(thor.BytesToAddress([]byte("other")), user) }, false, "should not be user of other"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.fn(), tt.msg)
+	}
+
+	assert.Nil(t, st.Err())
+}
+
+func TestCreditPlan(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	proto := prototype.New(thor.BytesToAddress([]byte("proto")), st)
+	self := thor.BytesToAddress([]byte("self"))
+	user := thor.BytesToAddress([]byte("user"))
+	credit := big.NewInt(1000)
+	recoveryRate := big.NewInt(10)
+
+	tests := []struct {
+		fn       func() interface{}
+		expected interface{}
+		msg      string
+	}{
+		{func() interface{} { return M(proto.CreditPlan(self)) }, []interface{}{&big.Int{}, &big.Int{}}, "should be zero plan"},
+		{func() interface{} { proto.SetCreditPlan(self, credit, recoveryRate); return nil }, nil, ""},
+		{func() interface{} { return M(proto.CreditPlan(self)) }, []interface{}{credit, recoveryRate}, "should set plan"},
+		{func() interface{} { return M(proto.Bind(self).UserPlan()) }, []interface{}{credit, recoveryRate}, "should be plan of binding"},
+
+		{func() interface{} { proto.Bind(self).SetUserCredit(user, &big.Int{}, 1); return nil }, nil, ""},
+		{func() interface{} { return proto.Bind(self).UserCredit(user, 1) }, &big.Int{}, "should have no credit"},
+		{func() interface{} { return proto.Bind(self).UserCredit(user, 11) }, big.NewInt(100), "should recover credit over time"},
+		{func() interface{} { return proto.Bind(self).UserCredit(user, 51) }, big.NewInt(500), "should recover more credit over more time"},
+		{func() interface{} { return proto.Bind(self).UserCredit(user, 1001) }, credit, "should recover up to the plan credit"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.fn(), tt.msg)
+	}
+
+	assert.Nil(t, st.Err())
+}
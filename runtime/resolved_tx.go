@@ -92,7 +92,7 @@ func (r *ResolvedTransaction) BuyGas(state *state.State, blockTime uint64) (
 	payer thor.Address,
 	returnGas func(uint64), err error) {
 
-	baseGasPrice = builtin.Params.Native(state).Get(thor.KeyBaseGasPrice)
+	baseGasPrice = builtin.Params.Native(state).BaseGasPrice()
 	gasPrice = r.tx.GasPrice(baseGasPrice)
 
 	energy := builtin.Energy.Native(state, blockTime)
@@ -103,35 +103,64 @@ func (r *ResolvedTransaction) BuyGas(state *state.State, blockTime uint64) (
 	}
 
 	prepaid := new(big.Int).Mul(new(big.Int).SetUint64(r.tx.Gas()), gasPrice)
+	payer, credited, err := r.resolvePayer(state, blockTime, prepaid)
+	if err != nil {
+		return nil, nil, thor.Address{}, nil, err
+	}
+	energy.Sub(payer, prepaid)
+
+	if credited {
+		binding := builtin.Prototype.Native(state).Bind(*r.CommonTo())
+		credit := binding.UserCredit(r.Origin, blockTime)
+		return baseGasPrice, gasPrice, payer, func(rgas uint64) {
+			returnedEnergy := doReturnGas(rgas)
+			usedEnergy := new(big.Int).Sub(prepaid, returnedEnergy)
+			binding.SetUserCredit(r.Origin, new(big.Int).Sub(credit, usedEnergy), blockTime)
+		}, nil
+	}
+	return baseGasPrice, gasPrice, payer, func(rgas uint64) { doReturnGas(rgas) }, nil
+}
+
+// Payer returns the account BuyGas would consume energy from, without modifying state.
+func (r *ResolvedTransaction) Payer(state *state.State, blockTime uint64) (thor.Address, error) {
+	gasPrice := r.tx.GasPrice(builtin.Params.Native(state).BaseGasPrice())
+	prepaid := new(big.Int).Mul(new(big.Int).SetUint64(r.tx.Gas()), gasPrice)
+	payer, _, err := r.resolvePayer(state, blockTime, prepaid)
+	return payer, err
+}
+
+// resolvePayer returns the account affording prepaid energy, and whether it pays
+// on the user credit of the common 'To'.
+func (r *ResolvedTransaction) resolvePayer(state *state.State, blockTime uint64, prepaid *big.Int) (payer thor.Address, credited bool, err error) {
+	energy := builtin.Energy.Native(state, blockTime)
+	affords := func(addr thor.Address) bool {
+		return energy.Get(addr).Cmp(prepaid) >= 0
+	}
+
 	commonTo := r.CommonTo()
 	if commonTo != nil {
 		binding := builtin.Prototype.Native(state).Bind(*commonTo)
 		credit := binding.UserCredit(r.Origin, blockTime)
 		if credit.Cmp(prepaid) >= 0 {
-			doReturnGasAndSetCredit := func(rgas uint64) {
-				returnedEnergy := doReturnGas(rgas)
-				usedEnergy := new(big.Int).Sub(prepaid, returnedEnergy)
-				binding.SetUserCredit(r.Origin, new(big.Int).Sub(credit, usedEnergy), blockTime)
-			}
 			// has enough credit
 			if sponsor := binding.CurrentSponsor(); binding.IsSponsor(sponsor) {
 				// deduct from sponsor, if any
-				if energy.Sub(sponsor, prepaid) {
-					return baseGasPrice, gasPrice, sponsor, doReturnGasAndSetCredit, nil
+				if affords(sponsor) {
+					return sponsor, true, nil
 				}
 			}
 			// deduct from To
-			if energy.Sub(*commonTo, prepaid) {
-				return baseGasPrice, gasPrice, *commonTo, doReturnGasAndSetCredit, nil
+			if affords(*commonTo) {
+				return *commonTo, true, nil
 			}
 		}
 	}
 
 	// fallback to deduct from tx origin
-	if energy.Sub(r.Origin, prepaid) {
-		return baseGasPrice, gasPrice, r.Origin, func(rgas uint64) { doReturnGas(rgas) }, nil
+	if affords(r.Origin) {
+		return r.Origin, false, nil
 	}
-	return nil, nil, thor.Address{}, nil, errors.New("insufficient energy")
+	return thor.Address{}, false, errors.New("insufficient energy")
 }
 
 // ToContext create a tx context object.
@@ -6,7 +6,10 @@
 package runtime
 
 import (
+	"bytes"
+	"context"
 	"math/big"
+	"reflect"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -56,13 +59,126 @@ var chainConfig = params.ChainConfig{
 
 // Output output of clause execution.
 type Output struct {
-	Data            []byte
-	Events          tx.Events
-	Transfers       tx.Transfers
-	LeftOverGas     uint64
-	RefundGas       uint64
-	VMErr           error         // VMErr identify the execution result of the contract function, not evm function's err.
-	ContractAddress *thor.Address // if create a new contract, or is nil.
+	Data             []byte
+	Events           tx.Events
+	Transfers        tx.Transfers
+	LeftOverGas      uint64
+	RefundGas        uint64
+	VMErr            error          // VMErr identify the execution result of the contract function, not evm function's err. Compare its errors.Cause with vm errors.
+	ContractAddress  *thor.Address  // if create a new contract, or is nil.
+	PendingDestruct  []thor.Address // contracts self-destructed by the clause, pending commit of state.
+	MaxStackDepth    int            // the deepest stack size reached, if vm.Config.TrackStack set.
+	Reaped           []thor.Address // accounts touched and left empty, deleted and sorted, if vm.Config.ReapEmpty set.
+	TouchedAddresses []thor.Address // addresses read or written, deduplicated and sorted, if vm.Config.TrackTouched set.
+}
+
+// GasUsed returns gas used by the execution, given the gas supplied to it.
+func (o *Output) GasUsed(suppliedGas uint64) uint64 {
+	return suppliedGas - o.LeftOverGas
+}
+
+var (
+	// selectors of builtin Error(string) and Panic(uint256) errors
+	errorSelector = [4]byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector = [4]byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// RevertSelector returns the 4-byte selector and raw args of the custom error the execution reverted with.
+// False returned if not reverted, or reverted with Error(string), Panic(uint256) or no selector.
+func (o *Output) RevertSelector() ([4]byte, []byte, bool) {
+	var selector [4]byte
+	if o.VMErr == nil || len(o.Data) < 4 {
+		return selector, nil, false
+	}
+	copy(selector[:], o.Data)
+	if selector == errorSelector || selector == panicSelector {
+		return selector, nil, false
+	}
+	return selector, o.Data[4:], true
+}
+
+// ReturnedGas returns gas to be returned after the execution, given the gas supplied to it.
+// That's the left over gas, plus the refund capped to half of the used gas.
+func (o *Output) ReturnedGas(suppliedGas uint64) uint64 {
+	refund := o.GasUsed(suppliedGas) / 2
+	if refund > o.RefundGas {
+		refund = o.RefundGas
+	}
+	// won't overflow
+	return o.LeftOverGas + refund
+}
+
+// FilterEvents returns events emitted by the given address, whose leading topics match the given topics.
+// A nil topic matches any.
+func (o *Output) FilterEvents(address thor.Address, topics []*thor.Bytes32) tx.Events {
+	var filtered tx.Events
+next:
+	for _, ev := range o.Events {
+		if ev.Address != address || len(ev.Topics) < len(topics) {
+			continue
+		}
+		for i, topic := range topics {
+			if topic != nil && *topic != ev.Topics[i] {
+				continue next
+			}
+		}
+		filtered = append(filtered, ev)
+	}
+	return filtered
+}
+
+// BalanceChanges returns net balance changes of accounts involved in transfers,
+// negative for accounts paid out more than received.
+func (o *Output) BalanceChanges() map[thor.Address]*big.Int {
+	changes := make(map[thor.Address]*big.Int)
+	change := func(addr thor.Address) *big.Int {
+		if c, ok := changes[addr]; ok {
+			return c
+		}
+		c := new(big.Int)
+		changes[addr] = c
+		return c
+	}
+	for _, tr := range o.Transfers {
+		change(tr.Sender).Sub(change(tr.Sender), tr.Amount)
+		change(tr.Recipient).Add(change(tr.Recipient), tr.Amount)
+	}
+	return changes
+}
+
+// BatchResult outputs of clauses executed by ExecuteClauses.
+type BatchResult struct {
+	Outputs []*Output // outputs of clauses in order
+	Gas     uint64    // gas supplied to the batch
+}
+
+// AllLogs returns events emitted by all successful clauses in order.
+func (br *BatchResult) AllLogs() tx.Events {
+	var all tx.Events
+	for _, o := range br.Outputs {
+		if o.VMErr != nil {
+			continue
+		}
+		all = append(all, o.Events...)
+	}
+	return all
+}
+
+// CumulativeGasUsed returns total gas used by the batch after each clause.
+func (br *BatchResult) CumulativeGasUsed() []uint64 {
+	cumulative := make([]uint64, 0, len(br.Outputs))
+	for _, o := range br.Outputs {
+		cumulative = append(cumulative, o.GasUsed(br.Gas))
+	}
+	return cumulative
+}
+
+// SimulatedReceipt summarizes clauses executed by Simulate.
+type SimulatedReceipt struct {
+	GasUsed  uint64    // gas used by clauses, excluding intrinsic gas
+	Reverted bool      // whether any clause failed
+	Outputs  []*Output // outputs of clauses executed, up to the failed one
+	Logs     tx.Events // events emitted, empty if reverted
 }
 
 // Runtime bases on EVM and VeChain Thor builtins.
@@ -90,6 +206,17 @@ func (rt *Runtime) Seeker() *chain.Seeker       { return rt.seeker }
 func (rt *Runtime) State() *state.State         { return rt.state }
 func (rt *Runtime) Context() *xenv.BlockContext { return rt.ctx }
 
+// DeployCodeAt sets runtime code of the account at addr directly, without running any init code.
+// It's for test fixtures.
+func (rt *Runtime) DeployCodeAt(addr thor.Address, code []byte) {
+	rt.state.SetCode(addr, code)
+}
+
+// StateRoot computes root of the accounts trie, including changes made by executions so far.
+func (rt *Runtime) StateRoot() (thor.Bytes32, error) {
+	return rt.state.Stage().Hash()
+}
+
 // SetVMConfig config VM.
 // Returns this runtime.
 func (rt *Runtime) SetVMConfig(config vm.Config) *Runtime {
@@ -99,6 +226,11 @@ func (rt *Runtime) SetVMConfig(config vm.Config) *Runtime {
 
 func (rt *Runtime) newEVM(stateDB *statedb.StateDB, clauseIndex uint32, txCtx *xenv.TransactionContext) *vm.EVM {
 	var lastNonNativeCallGas uint64
+	gasPrice := txCtx.GasPrice
+	if gasPrice == nil && rt.vmConfig.GasOracle == nil {
+		// fallback to base gas price, unless the oracle suggests
+		gasPrice = builtin.Params.Native(rt.state).BaseGasPrice()
+	}
 	return vm.NewEVM(vm.Context{
 		CanTransfer: func(_ vm.StateDB, addr common.Address, amount *big.Int) bool {
 			return stateDB.GetBalance(addr).Cmp(amount) >= 0
@@ -129,6 +261,9 @@ func (rt *Runtime) newEVM(stateDB *statedb.StateDB, clauseIndex uint32, txCtx *x
 		NewContractAddress: func(_ *vm.EVM, counter uint32) common.Address {
 			return common.Address(thor.CreateContractAddress(txCtx.ID, clauseIndex, counter))
 		},
+		IsPaused: func(_ vm.StateDB, addr common.Address) bool {
+			return builtin.Prototype.Native(rt.state).IsPaused(thor.Address(addr))
+		},
 		InterceptContractCall: func(evm *vm.EVM, contract *vm.Contract, readonly bool) ([]byte, error, bool) {
 			if evm.Depth() < 2 {
 				lastNonNativeCallGas = contract.Gas
@@ -149,7 +284,7 @@ func (rt *Runtime) newEVM(stateDB *statedb.StateDB, clauseIndex uint32, txCtx *x
 			}
 
 			if readonly && !abi.Const() {
-				panic("invoke non-const method in readonly env")
+				return nil, vm.ErrWriteProtection, true
 			}
 
 			if contract.Value().Sign() != 0 {
@@ -221,7 +356,7 @@ func (rt *Runtime) newEVM(stateDB *statedb.StateDB, clauseIndex uint32, txCtx *x
 			}
 		},
 		Origin:      common.Address(txCtx.Origin),
-		GasPrice:    txCtx.GasPrice,
+		GasPrice:    gasPrice,
 		Coinbase:    common.Address(rt.ctx.Beneficiary),
 		GasLimit:    rt.ctx.GasLimit,
 		BlockNumber: new(big.Int).SetUint64(uint64(rt.ctx.Number)),
@@ -236,15 +371,70 @@ func (rt *Runtime) ExecuteClause(
 	clauseIndex uint32,
 	gas uint64,
 	txCtx *xenv.TransactionContext,
+) *Output {
+	return rt.ExecuteClauseWithContext(context.Background(), clause, clauseIndex, gas, txCtx)
+}
+
+// ExecuteClauseWithContext executes single clause, which is aborted with vm.ErrAborted
+// once the context is done.
+func (rt *Runtime) ExecuteClauseWithContext(
+	ctx context.Context,
+	clause *tx.Clause,
+	clauseIndex uint32,
+	gas uint64,
+	txCtx *xenv.TransactionContext,
+) *Output {
+	return rt.executeClause(ctx, clause, clauseIndex, gas, txCtx, newTxScope())
+}
+
+// txScope holds execution state shared by clauses of a transaction.
+type txScope struct {
+	storageWrites  int               // counted against vm.Config.MaxStorageWrites
+	logs           int               // counted against vm.Config.MaxLogs
+	transient      statedb.Transient // discarded with the scope
+	originalStates vm.OriginalStates // for net gas metering of vm.Config.EIP2200
+}
+
+func newTxScope() *txScope {
+	return &txScope{
+		transient:      make(statedb.Transient),
+		originalStates: make(vm.OriginalStates),
+	}
+}
+
+func (rt *Runtime) executeClause(
+	ctx context.Context,
+	clause *tx.Clause,
+	clauseIndex uint32,
+	gas uint64,
+	txCtx *xenv.TransactionContext,
+	scope *txScope,
 ) *Output {
 	var (
-		stateDB      = statedb.New(rt.state)
+		stateDB      = statedb.NewWithTransient(rt.state, scope.transient)
 		evm          = rt.newEVM(stateDB, clauseIndex, txCtx)
 		data         []byte
 		leftOverGas  uint64
 		vmErr        error
 		contractAddr *thor.Address
 	)
+	evm.SetStorageWrites(scope.storageWrites)
+	evm.SetLogCount(scope.logs)
+	evm.SetOriginalStates(scope.originalStates)
+	if rt.vmConfig.TrackTouched || rt.vmConfig.ReapEmpty {
+		stateDB.TrackTouched()
+	}
+	if done := ctx.Done(); done != nil {
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case <-done:
+				evm.Cancel()
+			case <-finished:
+			}
+		}()
+	}
 	if clause.To() == nil {
 		var caddr common.Address
 		data, caddr, leftOverGas, vmErr = evm.Create(vm.AccountRef(txCtx.Origin), clause.Data(), gas, clause.Value())
@@ -252,6 +442,8 @@ func (rt *Runtime) ExecuteClause(
 	} else {
 		data, leftOverGas, vmErr = evm.Call(vm.AccountRef(txCtx.Origin), common.Address(*clause.To()), clause.Data(), gas, clause.Value())
 	}
+	scope.storageWrites = evm.StorageWrites()
+	scope.logs = evm.LogCount()
 
 	output := &Output{
 		Data:            data,
@@ -259,11 +451,119 @@ func (rt *Runtime) ExecuteClause(
 		RefundGas:       stateDB.GetRefund(),
 		VMErr:           vmErr,
 		ContractAddress: contractAddr,
+		MaxStackDepth:   evm.MaxStackDepth(),
 	}
 	output.Events, output.Transfers = stateDB.GetLogs()
+	stateDB.CommitTransient()
+	output.PendingDestruct = stateDB.GetSuicided()
+	if rt.vmConfig.ReapEmpty {
+		output.Reaped = rt.state.Reap(stateDB.GetTouched())
+	}
+	if rt.vmConfig.TrackTouched {
+		output.TouchedAddresses = stateDB.GetTouched()
+	}
 	return output
 }
 
+// CompareConfigs executes the clause under VM config a and b in turn, and reports whether
+// both outputs are equal, regardless of gas. State changes made by both executions are reverted.
+func (rt *Runtime) CompareConfigs(
+	clause *tx.Clause,
+	clauseIndex uint32,
+	gas uint64,
+	txCtx *xenv.TransactionContext,
+	a, b vm.Config,
+) (outA, outB *Output, equal bool) {
+	origin := rt.vmConfig
+	defer rt.SetVMConfig(origin)
+
+	exec := func(config vm.Config) *Output {
+		checkpoint := rt.state.NewCheckpoint()
+		defer rt.state.RevertTo(checkpoint)
+		return rt.SetVMConfig(config).ExecuteClause(clause, clauseIndex, gas, txCtx)
+	}
+	outA, outB = exec(a), exec(b)
+	return outA, outB, outputEqual(outA, outB)
+}
+
+func outputEqual(a, b *Output) bool {
+	if (a.VMErr == nil) != (b.VMErr == nil) ||
+		(a.VMErr != nil && a.VMErr.Error() != b.VMErr.Error()) {
+		return false
+	}
+	return bytes.Equal(a.Data, b.Data) &&
+		reflect.DeepEqual(a.Events, b.Events) &&
+		reflect.DeepEqual(a.Transfers, b.Transfers) &&
+		reflect.DeepEqual(a.ContractAddress, b.ContractAddress)
+}
+
+// ExecuteClauses executes clauses in turn, sharing the given gas.
+// Unlike ExecuteTransaction, a failed clause doesn't abort the rest.
+// Transient storage persists across the clauses, and is discarded at the end.
+func (rt *Runtime) ExecuteClauses(clauses []*tx.Clause, gas uint64, txCtx *xenv.TransactionContext) *BatchResult {
+	result := &BatchResult{Outputs: make([]*Output, 0, len(clauses)), Gas: gas}
+	scope := newTxScope()
+	for i, clause := range clauses {
+		output := rt.executeClause(context.Background(), clause, uint32(i), gas, txCtx, scope)
+		gas = output.LeftOverGas
+		result.Outputs = append(result.Outputs, output)
+	}
+	return result
+}
+
+// Simulate executes clauses as a transaction does, which stops at the first failed clause.
+// State changes are always reverted.
+func (rt *Runtime) Simulate(clauses []*tx.Clause, gas uint64, txCtx *xenv.TransactionContext) *SimulatedReceipt {
+	checkpoint := rt.state.NewCheckpoint()
+	defer rt.state.RevertTo(checkpoint)
+
+	receipt := &SimulatedReceipt{}
+	leftOverGas := gas
+	scope := newTxScope()
+	for i, clause := range clauses {
+		output := rt.executeClause(context.Background(), clause, uint32(i), leftOverGas, txCtx, scope)
+		leftOverGas = output.LeftOverGas
+		receipt.Outputs = append(receipt.Outputs, output)
+		if output.VMErr != nil {
+			receipt.Reverted = true
+			break
+		}
+		receipt.Logs = append(receipt.Logs, output.Events...)
+	}
+	receipt.GasUsed = gas - leftOverGas
+	if receipt.Reverted {
+		receipt.Logs = nil
+	}
+	return receipt
+}
+
+// Finalize credits energy of gas returned by the execution to the payer, at the given gas price.
+// It returns the energy credited.
+func (rt *Runtime) Finalize(output *Output, suppliedGas uint64, payer thor.Address, gasPrice *big.Int) (refunded *big.Int) {
+	refunded = new(big.Int).SetUint64(output.ReturnedGas(suppliedGas))
+	refunded.Mul(refunded, gasPrice)
+	builtin.Energy.Native(rt.state, rt.ctx.Time).Add(payer, refunded)
+	return refunded
+}
+
+// ExecuteSignedClauses recovers origin from the signature over clauses and gas limit,
+// then executes them as a transaction with other fields left zero.
+// The recovered origin is returned even if the transaction fails to execute.
+func (rt *Runtime) ExecuteSignedClauses(clauses []*tx.Clause, gas uint64, sig []byte) (origin thor.Address, receipt *tx.Receipt, err error) {
+	builder := new(tx.Builder).Gas(gas)
+	for _, c := range clauses {
+		builder.Clause(c)
+	}
+	signed := builder.Build().WithSignature(sig)
+	if origin, err = signed.Signer(); err != nil {
+		return thor.Address{}, nil, err
+	}
+	if receipt, err = rt.ExecuteTransaction(signed); err != nil {
+		return origin, nil, err
+	}
+	return origin, receipt, nil
+}
+
 // ExecuteTransaction executes a transaction.
 // If some clause failed, receipt.Outputs will be nil and vmOutputs may shorter than clause count.
 func (rt *Runtime) ExecuteTransaction(tx *tx.Transaction) (receipt *tx.Receipt, err error) {
@@ -271,6 +571,16 @@ func (rt *Runtime) ExecuteTransaction(tx *tx.Transaction) (receipt *tx.Receipt,
 	if err != nil {
 		return nil, err
 	}
+	if check := rt.vmConfig.SponsorCheck; check != nil {
+		// check before buying gas, so state is untouched if rejected
+		payer, err := resolvedTx.Payer(rt.state, rt.ctx.Time)
+		if err != nil {
+			return nil, err
+		}
+		if payer != resolvedTx.Origin && !check(common.Address(payer), common.Address(resolvedTx.Origin), tx.Gas()) {
+			return nil, vm.ErrSponsorRejected
+		}
+	}
 
 	baseGasPrice, gasPrice, payer, returnGas, err := resolvedTx.BuyGas(rt.state, rt.ctx.Time)
 	if err != nil {
@@ -285,20 +595,11 @@ func (rt *Runtime) ExecuteTransaction(tx *tx.Transaction) (receipt *tx.Receipt,
 	receipt = &Tx.Receipt{Outputs: make([]*Tx.Output, 0, len(resolvedTx.Clauses))}
 
 	txCtx := resolvedTx.ToContext(gasPrice, rt.ctx.Number, rt.seeker.GetID)
+	scope := newTxScope()
 	for i, clause := range resolvedTx.Clauses {
-		output := rt.ExecuteClause(clause, uint32(i), leftOverGas, txCtx)
+		output := rt.executeClause(context.Background(), clause, uint32(i), leftOverGas, txCtx, scope)
 
-		gasUsed := leftOverGas - output.LeftOverGas
-		leftOverGas = output.LeftOverGas
-
-		// Apply refund counter, capped to half of the used gas.
-		refund := gasUsed / 2
-		if refund > output.RefundGas {
-			refund = output.RefundGas
-		}
-
-		// won't overflow
-		leftOverGas += refund
+		leftOverGas = output.ReturnedGas(leftOverGas)
 
 		if output.VMErr != nil {
 			// vm exception here
@@ -331,3 +632,20 @@ func (rt *Runtime) ExecuteTransaction(tx *tx.Transaction) (receipt *tx.Receipt,
 
 	return receipt, nil
 }
+
+// ReplayBlock executes transactions of a block in turn, and returns the resulting state root
+// along with receipts, for verifying the block during sync.
+func (rt *Runtime) ReplayBlock(txs tx.Transactions) (stateRoot thor.Bytes32, receipts tx.Receipts, err error) {
+	receipts = make(tx.Receipts, 0, len(txs))
+	for _, transaction := range txs {
+		receipt, err := rt.ExecuteTransaction(transaction)
+		if err != nil {
+			return thor.Bytes32{}, nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	if stateRoot, err = rt.StateRoot(); err != nil {
+		return thor.Bytes32{}, nil, err
+	}
+	return stateRoot, receipts, nil
+}
@@ -6,12 +6,19 @@
 package runtime_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	ethmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/vechain/thor/abi"
 	"github.com/vechain/thor/builtin"
@@ -22,6 +29,8 @@ import (
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
 	"github.com/vechain/thor/tx"
+	"github.com/vechain/thor/vm"
+	"github.com/vechain/thor/vm/vmtest"
 	"github.com/vechain/thor/xenv"
 )
 
@@ -139,6 +148,425 @@ func TestCall(t *testing.T) {
 	assert.Equal(t, thor.Address(addr), genesis.DevAccounts()[0].Address)
 }
 
+func TestDeployCodeAt(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+	emptyRoot, _ := rt.StateRoot()
+
+	// natives are bound to the builtin address
+	rt.DeployCodeAt(builtin.Params.Address, builtin.Params.RuntimeBytecodes())
+	assert.Equal(t, builtin.Params.RuntimeBytecodes(), state.GetCode(builtin.Params.Address))
+
+	// tracked as a change
+	root, _ := rt.StateRoot()
+	assert.NotEqual(t, emptyRoot, root)
+
+	method, _ := builtin.Params.ABI.MethodByName("executor")
+	data, err := method.EncodeInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rt.ExecuteClause(
+		tx.NewClause(&builtin.Params.Address).WithData(data),
+		0, math.MaxUint64, &xenv.TransactionContext{})
+	if out.VMErr != nil {
+		t.Fatal(out.VMErr)
+	}
+
+	var addr common.Address
+	if err := method.DecodeOutput(out.Data, &addr); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, common.Address{}, addr)
+}
+
+func TestGasUsed(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, _ := chain.New(kv, b0)
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+	rt := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{})
+
+	method, _ := builtin.Params.ABI.MethodByName("executor")
+	data, err := method.EncodeInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suppliedGas := uint64(100000)
+	out := rt.ExecuteClause(
+		tx.NewClause(&builtin.Params.Address).WithData(data),
+		0, suppliedGas, &xenv.TransactionContext{})
+	if out.VMErr != nil {
+		t.Fatal(out.VMErr)
+	}
+
+	assert.NotZero(t, out.LeftOverGas)
+	assert.NotZero(t, out.GasUsed(suppliedGas))
+	assert.Equal(t, suppliedGas, out.LeftOverGas+out.GasUsed(suppliedGas))
+}
+
+func TestCodeSize(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+
+	assert.NotEmpty(t, state.GetCode(builtin.Params.Address))
+
+	// mstore(0, codesize()) return(0, 32), followed by the code called in TestCall
+	code := append(common.Hex2Bytes("3860005260206000f3"), state.GetCode(builtin.Params.Address)...)
+	probe := thor.BytesToAddress([]byte("probe"))
+	state.SetCode(probe, code)
+
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClause(tx.NewClause(&probe), 0, math.MaxUint64, &xenv.TransactionContext{})
+	if out.VMErr != nil {
+		t.Fatal(out.VMErr)
+	}
+	assert.Equal(t, uint64(len(code)), new(big.Int).SetBytes(out.Data).Uint64())
+}
+
+func TestMaxStackDepth(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, _ := chain.New(kv, b0)
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+
+	// push(1) push(1) push(1) pop pop pop
+	pusher := thor.BytesToAddress([]byte("pusher"))
+	state.SetCode(pusher, common.Hex2Bytes("600160016001505050"))
+
+	method, _ := builtin.Params.ABI.MethodByName("executor")
+	data, err := method.EncodeInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := func(clause *tx.Clause, track bool) *runtime.Output {
+		out := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{TrackStack: track}).
+			ExecuteClause(clause, 0, math.MaxUint64, &xenv.TransactionContext{})
+		if out.VMErr != nil {
+			t.Fatal(out.VMErr)
+		}
+		return out
+	}
+
+	assert.Equal(t, 3, exec(tx.NewClause(&pusher), true).MaxStackDepth)
+	assert.Zero(t, exec(tx.NewClause(&pusher), false).MaxStackDepth)
+
+	depth := exec(tx.NewClause(&builtin.Params.Address).WithData(data), true).MaxStackDepth
+	assert.True(t, depth > 3 && depth <= 1024, "unexpected max stack depth %d", depth)
+}
+
+func TestCallCoverage(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, _ := chain.New(kv, b0)
+
+	method, _ := builtin.Params.ABI.MethodByName("executor")
+	data, err := method.EncodeInput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var coverages []map[vm.OpCode]uint64
+	for i := 0; i < 2; i++ {
+		state, _ := state.New(b0.Header().StateRoot(), kv)
+		coverage := make(map[vm.OpCode]uint64)
+		out := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{CoverageMap: coverage}).
+			ExecuteClause(tx.NewClause(&builtin.Params.Address).WithData(data), 0, math.MaxUint64, &xenv.TransactionContext{})
+		if out.VMErr != nil {
+			t.Fatal(out.VMErr)
+		}
+		coverages = append(coverages, coverage)
+	}
+
+	for _, op := range []vm.OpCode{vm.PUSH1, vm.MSTORE, vm.RETURN} {
+		assert.NotZero(t, coverages[0][op], op.String())
+	}
+	assert.Zero(t, coverages[0][vm.SSTORE])
+
+	total := vm.AggregateCoverage(coverages...)
+	assert.Equal(t, 2*coverages[0][vm.PUSH1], total[vm.PUSH1])
+}
+
+func TestMaxStorageWrites(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, 1) sstore(1, 1) sstore(2, 1)
+	writer := thor.BytesToAddress([]byte("writer"))
+	state.SetCode(writer, common.Hex2Bytes("600160005560016001556001600255"))
+
+	// sstore(0, 1) sstore(1, 1) revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("6001600055600160015560006000fd"))
+
+	// call(gas, reverter, 0, 0, 0, 0, 0) sstore(0, 1) sstore(1, 1)
+	caller := thor.BytesToAddress([]byte("caller"))
+	state.SetCode(caller, common.Hex2Bytes("600060006000600060007f"+hex.EncodeToString(thor.BytesToBytes32(reverter.Bytes()).Bytes())+"5af15060016000556001600155"))
+
+	exec := func(to thor.Address, max int) *runtime.Output {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{MaxStorageWrites: max}).
+			ExecuteClause(tx.NewClause(&to), 0, math.MaxUint64, &xenv.TransactionContext{})
+	}
+
+	assert.Equal(t, vm.ErrStorageWriteLimit, errors.Cause(exec(writer, 2).VMErr))
+	assert.Nil(t, exec(writer, 3).VMErr)
+	assert.Nil(t, exec(writer, 0).VMErr)
+
+	// writes rolled back by the reverted call are not counted
+	assert.Nil(t, exec(caller, 3).VMErr)
+	assert.Equal(t, vm.ErrStorageWriteLimit, errors.Cause(exec(caller, 1).VMErr))
+
+	// the limit is shared by clauses of a transaction
+	batch := func(max int) []*runtime.Output {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{MaxStorageWrites: max}).
+			ExecuteClauses([]*tx.Clause{tx.NewClause(&writer), tx.NewClause(&writer)}, math.MaxUint64, &xenv.TransactionContext{}).
+			Outputs
+	}
+	outputs := batch(4)
+	assert.Nil(t, outputs[0].VMErr)
+	assert.Equal(t, vm.ErrStorageWriteLimit, errors.Cause(outputs[1].VMErr))
+	for _, o := range batch(6) {
+		assert.Nil(t, o.VMErr)
+	}
+}
+
+func TestCompareConfigs(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, 1) pop(create(0, 0, 0)) mstore(0, 1) return(0, 32)
+	creator := thor.BytesToAddress([]byte("creator"))
+	state.SetCode(creator, common.Hex2Bytes("6001600055600060006000f050600160005260206000f3"))
+
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+	clause := tx.NewClause(&creator)
+
+	outA, outB, equal := rt.CompareConfigs(clause, 0, math.MaxUint64, &xenv.TransactionContext{},
+		vm.Config{}, vm.Config{CreateGas: 100})
+	assert.True(t, equal)
+	assert.Nil(t, outA.VMErr)
+	assert.Equal(t, outA.Data, outB.Data)
+	assert.Equal(t, outB.LeftOverGas-outA.LeftOverGas, params.CreateGas-100)
+
+	// state changes are reverted
+	assert.Equal(t, thor.Bytes32{}, state.GetStorage(creator, thor.Bytes32{}))
+
+	outA, outB, equal = rt.CompareConfigs(clause, 0, math.MaxUint64, &xenv.TransactionContext{},
+		vm.Config{}, vm.Config{MaxReturnBytes: 1})
+	assert.False(t, equal)
+	assert.Nil(t, outA.VMErr)
+	assert.Equal(t, vm.ErrReturnTooLarge, errors.Cause(outB.VMErr))
+}
+
+func TestFilterEvents(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// log2(0, 0, 0xaa, 1) log2(0, 0, 0xaa, 2) log2(0, 0, 0xaa, 3)
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	state.SetCode(emitter, common.Hex2Bytes("600160aa60006000a2600260aa60006000a2600360aa60006000a2"))
+
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClause(tx.NewClause(&emitter), 0, math.MaxUint64, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	assert.Len(t, out.Events, 3)
+
+	topic0 := thor.BytesToBytes32([]byte{0xaa})
+	topic1 := thor.BytesToBytes32([]byte{2})
+
+	tests := []struct {
+		ret      interface{}
+		expected interface{}
+	}{
+		{len(out.FilterEvents(emitter, nil)), 3},
+		{len(out.FilterEvents(emitter, []*thor.Bytes32{&topic0})), 3},
+		{len(out.FilterEvents(emitter, []*thor.Bytes32{&topic1})), 0},
+		{out.FilterEvents(emitter, []*thor.Bytes32{nil, &topic1}), tx.Events{out.Events[1]}},
+		{out.FilterEvents(emitter, []*thor.Bytes32{&topic0, &topic1}), tx.Events{out.Events[1]}},
+		{len(out.FilterEvents(emitter, []*thor.Bytes32{nil, nil, nil})), 0},
+		{len(out.FilterEvents(thor.BytesToAddress([]byte("other")), nil)), 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.ret)
+	}
+}
+
+func TestSponsorCheck(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := chain.New(kv, b0)
+
+	var (
+		origin  = genesis.DevAccounts()[0].Address
+		to      = genesis.DevAccounts()[1].Address
+		sponsor = genesis.DevAccounts()[2].Address
+		ctx     = &xenv.BlockContext{Number: 1, Time: b0.Header().Timestamp() + thor.BlockInterval}
+	)
+
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+	bind := builtin.Prototype.Native(state).Bind(to)
+	bind.SetUserPlan(ethmath.MaxBig256, big.NewInt(1000))
+	bind.AddUser(origin, ctx.Time)
+	bind.Sponsor(sponsor, true)
+	bind.SelectSponsor(sponsor)
+
+	transaction := txSign(txBuilder(ch.Tag()).Clause(clause()))
+	exec := func(check func(gasPayer, origin common.Address, gas uint64) bool) (*tx.Receipt, error) {
+		return runtime.New(ch.NewSeeker(b0.Header().ID()), state, ctx).
+			SetVMConfig(vm.Config{SponsorCheck: check}).
+			ExecuteTransaction(transaction)
+	}
+
+	root, _ := state.Stage().Hash()
+	sponsorEnergy := state.GetEnergy(sponsor, ctx.Time)
+	_, err = exec(func(gasPayer, origin common.Address, gas uint64) bool {
+		// checked before gas bought
+		assert.Equal(t, sponsorEnergy, state.GetEnergy(sponsor, ctx.Time))
+		return false
+	})
+	assert.Equal(t, vm.ErrSponsorRejected, err)
+
+	// no state mutation
+	newRoot, _ := state.Stage().Hash()
+	assert.Equal(t, root, newRoot)
+
+	var checked []interface{}
+	receipt, err := exec(func(gasPayer, origin common.Address, gas uint64) bool {
+		checked = []interface{}{thor.Address(gasPayer), thor.Address(origin), gas}
+		return true
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, sponsor, receipt.GasPayer)
+	assert.Equal(t, []interface{}{sponsor, origin, transaction.Gas()}, checked)
+}
+
+func TestPendingDestruct(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// selfdestruct(0)
+	destructor := thor.BytesToAddress([]byte("destructor"))
+	state.SetCode(destructor, common.Hex2Bytes("6000ff"))
+
+	// pop(call(gas, destructor, 0, 0, 0, 0, 0)) revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("600060006000600060007f"+hex.EncodeToString(thor.BytesToBytes32(destructor.Bytes()).Bytes())+"5af15060006000fd"))
+
+	exec := func(to thor.Address) *runtime.Output {
+		checkpoint := state.NewCheckpoint()
+		defer state.RevertTo(checkpoint)
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			ExecuteClause(tx.NewClause(&to), 0, math.MaxUint64, &xenv.TransactionContext{})
+	}
+
+	out := exec(destructor)
+	assert.Nil(t, out.VMErr)
+	assert.Equal(t, []thor.Address{destructor}, out.PendingDestruct)
+
+	out = exec(reverter)
+	assert.NotNil(t, out.VMErr)
+	assert.Empty(t, out.PendingDestruct)
+	assert.NotEmpty(t, state.GetCode(destructor))
+}
+
+func TestStateRoot(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, add(sload(0), 1))
+	counter := thor.BytesToAddress([]byte("counter"))
+	state.SetCode(counter, common.Hex2Bytes("600160005401600055"))
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+
+	root0, err := rt.StateRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := rt.ExecuteClause(tx.NewClause(&counter), 0, math.MaxUint64, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	root1, err := rt.StateRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, root0, root1)
+
+	committed, _ := state.Stage().Commit()
+	assert.Equal(t, root1, committed)
+	assert.Equal(t, thor.BytesToBytes32([]byte{1}), state.GetStorage(counter, thor.Bytes32{}))
+
+	rt.ExecuteClause(tx.NewClause(&counter), 0, math.MaxUint64, &xenv.TransactionContext{})
+	root2, _ := rt.StateRoot()
+	assert.NotEqual(t, root1, root2)
+}
+
+func TestExecError(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// push1(1) push1(2) invalid
+	invalid := thor.BytesToAddress([]byte("invalid"))
+	state.SetCode(invalid, common.Hex2Bytes("60016002fe"))
+
+	// revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("60006000fd"))
+
+	exec := func(to thor.Address) *runtime.Output {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			ExecuteClause(tx.NewClause(&to), 0, math.MaxUint64, &xenv.TransactionContext{})
+	}
+
+	execErr, ok := exec(invalid).VMErr.(*vm.ExecError)
+	if !ok {
+		t.Fatal("expected *vm.ExecError")
+	}
+	assert.Equal(t, uint64(4), execErr.PC)
+	assert.Equal(t, vm.OpCode(0xfe), execErr.Op)
+	assert.Equal(t, vm.ErrInvalidOpcode, errors.Cause(execErr))
+
+	// revert is not a failure of the interpreter
+	_, ok = exec(reverter).VMErr.(*vm.ExecError)
+	assert.False(t, ok)
+}
+
 func TestExecuteTransaction(t *testing.T) {
 
 	// kv, _ := lvldb.NewMem()
@@ -177,3 +605,587 @@ func TestExecuteTransaction(t *testing.T) {
 	// _ = receipt
 	// assert.Equal(t, state.GetBalance(addr1), new(big.Int).Sub(balance1, big.NewInt(10)))
 }
+
+func TestExecuteSignedClauses(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := chain.New(kv, b0)
+
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+	rt := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{Number: 1, Time: b0.Header().Timestamp() + thor.BlockInterval})
+
+	acc := genesis.DevAccounts()[0]
+	to := genesis.DevAccounts()[1].Address
+	clauses := []*tx.Clause{tx.NewClause(&to).WithValue(big.NewInt(1))}
+
+	origin, sig := vmtest.SignedCall(acc.PrivateKey, clauses, 21000)
+	assert.Equal(t, acc.Address, origin)
+
+	recovered, receipt, err := rt.ExecuteSignedClauses(clauses, 21000, sig)
+	assert.Nil(t, err)
+	assert.Equal(t, origin, recovered)
+	assert.Equal(t, origin, receipt.GasPayer)
+	assert.False(t, receipt.Reverted)
+
+	// signature doesn't match tampered gas or clauses, so another origin is recovered
+	for _, tampered := range []struct {
+		clauses []*tx.Clause
+		gas     uint64
+	}{
+		{clauses, 21001},
+		{[]*tx.Clause{tx.NewClause(&to).WithValue(big.NewInt(2))}, 21000},
+	} {
+		recovered, _, err := rt.ExecuteSignedClauses(tampered.clauses, tampered.gas, sig)
+		assert.False(t, recovered.IsZero())
+		assert.NotEqual(t, origin, recovered)
+		// which has no energy to pay
+		assert.NotNil(t, err)
+
+		state.SetBalance(recovered, big.NewInt(2))
+		state.SetEnergy(recovered, new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1000)), rt.Context().Time)
+		recovered2, receipt, err := rt.ExecuteSignedClauses(tampered.clauses, tampered.gas, sig)
+		assert.Nil(t, err)
+		assert.Equal(t, recovered, recovered2)
+		assert.Equal(t, recovered, receipt.GasPayer)
+	}
+}
+
+func TestBatchResultAllLogs(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// log0(0, 0) stop
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	state.SetCode(emitter, common.Hex2Bytes("60006000a000"))
+	// log0(0, 0) revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("60006000a060006000fd"))
+
+	result := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClauses([]*tx.Clause{tx.NewClause(&reverter), tx.NewClause(&emitter)}, 100000, &xenv.TransactionContext{})
+	assert.Len(t, result.Outputs, 2)
+	assert.NotNil(t, result.Outputs[0].VMErr)
+	assert.Nil(t, result.Outputs[1].VMErr)
+
+	logs := result.AllLogs()
+	assert.Len(t, logs, 1)
+	assert.Equal(t, emitter, logs[0].Address)
+}
+
+func TestReapEmpty(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	from := thor.BytesToAddress([]byte("from"))
+	ghost := thor.BytesToAddress([]byte("ghost"))
+	// call(gas, ghost, 0, 0, 0, 0, 0) stop
+	caller := thor.BytesToAddress([]byte("caller"))
+	state.SetCode(caller, common.Hex2Bytes("6000600060006000600073"+hex.EncodeToString(ghost.Bytes())+"5af100"))
+	clause := tx.NewClause(&caller).WithValue(big.NewInt(100))
+
+	for _, reap := range []bool{false, true} {
+		state.SetBalance(from, big.NewInt(100))
+		state.SetBalance(caller, &big.Int{})
+
+		out := runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{ReapEmpty: reap}).
+			ExecuteClause(clause, 0, math.MaxUint64, &xenv.TransactionContext{Origin: from})
+		assert.Nil(t, out.VMErr)
+		assert.Equal(t, big.NewInt(100), state.GetBalance(caller))
+		// touching doesn't create
+		assert.False(t, state.Exists(ghost))
+		if reap {
+			// emptied sender and touched ghost, but not caller with code
+			assert.Equal(t, []thor.Address{from, ghost}, out.Reaped)
+		} else {
+			assert.Nil(t, out.Reaped)
+		}
+	}
+}
+
+func TestCreateSelfAddress(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// init code: sstore(0, address) stop
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClause(tx.NewClause(nil).WithData(common.Hex2Bytes("3060005500")), 0, math.MaxUint64, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	assert.NotNil(t, out.ContractAddress)
+	assert.Equal(t, thor.BytesToBytes32(out.ContractAddress.Bytes()), state.GetStorage(*out.ContractAddress, thor.Bytes32{}))
+}
+
+func TestExecuteClauseWithContext(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// loop: sstore(0, 1) jump(0)
+	looper := thor.BytesToAddress([]byte("looper"))
+	state.SetCode(looper, common.Hex2Bytes("5b6001600055600056"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClauseWithContext(ctx, tx.NewClause(&looper), 0, math.MaxUint64, &xenv.TransactionContext{})
+	assert.Equal(t, vm.ErrAborted, errors.Cause(out.VMErr))
+	assert.True(t, time.Since(start) < 5*time.Second, "should abort promptly")
+	// changes are reverted
+	assert.Equal(t, thor.Bytes32{}, state.GetStorage(looper, thor.Bytes32{}))
+}
+
+func TestMaxLogs(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// log0(0, 0) log0(0, 0) log0(0, 0)
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	state.SetCode(emitter, common.Hex2Bytes("60006000a060006000a060006000a0"))
+
+	// log0(0, 0) log0(0, 0) revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("60006000a060006000a060006000fd"))
+
+	// call(gas, reverter, 0, 0, 0, 0, 0) log0(0, 0) log0(0, 0)
+	caller := thor.BytesToAddress([]byte("caller"))
+	state.SetCode(caller, common.Hex2Bytes("600060006000600060007f"+hex.EncodeToString(thor.BytesToBytes32(reverter.Bytes()).Bytes())+"5af15060006000a060006000a0"))
+
+	exec := func(to thor.Address, max int) *runtime.Output {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{MaxLogs: max}).
+			ExecuteClause(tx.NewClause(&to), 0, math.MaxUint64, &xenv.TransactionContext{})
+	}
+
+	assert.Equal(t, vm.ErrLogLimit, errors.Cause(exec(emitter, 2).VMErr))
+	assert.Len(t, exec(emitter, 3).Events, 3)
+	assert.Len(t, exec(emitter, 0).Events, 3)
+
+	// logs rolled back by the reverted call are not counted
+	assert.Len(t, exec(caller, 2).Events, 2)
+	assert.Equal(t, vm.ErrLogLimit, errors.Cause(exec(caller, 1).VMErr))
+
+	// the limit is shared by clauses of a transaction
+	batch := func(max int) []*runtime.Output {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{MaxLogs: max}).
+			ExecuteClauses([]*tx.Clause{tx.NewClause(&emitter), tx.NewClause(&emitter)}, math.MaxUint64, &xenv.TransactionContext{}).
+			Outputs
+	}
+	outputs := batch(4)
+	assert.Len(t, outputs[0].Events, 3)
+	assert.Equal(t, vm.ErrLogLimit, errors.Cause(outputs[1].VMErr))
+	for _, o := range batch(6) {
+		assert.Len(t, o.Events, 3)
+	}
+}
+
+func TestFormatTrace(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := chain.New(kv, b0)
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+
+	logger := vm.NewStructLogger(nil)
+	rt := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{}).
+		SetVMConfig(vm.Config{Debug: true, Tracer: logger})
+
+	method, _ := builtin.Params.ABI.MethodByName("executor")
+	data, _ := method.EncodeInput()
+	out := rt.ExecuteClause(
+		tx.NewClause(&builtin.Params.Address).WithData(data),
+		0, math.MaxUint64, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+
+	text := vm.FormatTrace(logger.StructLogs())
+	assert.Equal(t, len(logger.StructLogs()), strings.Count(text, "\n"))
+
+	// solidity dispatcher prologue: mstore(0x40, 0x80) ... calldatasize
+	rest := text
+	for _, op := range []string{"op=PUSH1 ", "op=PUSH1 ", "op=MSTORE ", "op=CALLDATASIZE ", "op=RETURN "} {
+		i := strings.Index(rest, op)
+		if !assert.True(t, i >= 0, "missing %s", op) {
+			break
+		}
+		rest = rest[i+len(op):]
+	}
+	assert.Contains(t, text, "pc=00000000 op=PUSH1")
+}
+
+func TestBatchResultCumulativeGasUsed(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, 1)
+	writer := thor.BytesToAddress([]byte("writer"))
+	state.SetCode(writer, common.Hex2Bytes("600160005500"))
+	// log0(0, 0)
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	state.SetCode(emitter, common.Hex2Bytes("60006000a000"))
+
+	clauses := []*tx.Clause{tx.NewClause(&writer), tx.NewClause(&emitter), tx.NewClause(&emitter)}
+	result := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClauses(clauses, 100000, &xenv.TransactionContext{})
+
+	cumulative := result.CumulativeGasUsed()
+	assert.Len(t, cumulative, 3)
+
+	var sum, supplied uint64 = 0, result.Gas
+	for i, o := range result.Outputs {
+		assert.Nil(t, o.VMErr)
+		sum += o.GasUsed(supplied)
+		supplied = o.LeftOverGas
+		assert.Equal(t, sum, cumulative[i])
+		if i > 0 {
+			assert.True(t, cumulative[i] > cumulative[i-1])
+		}
+	}
+}
+
+func TestCallInsufficientBalance(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	target := thor.BytesToAddress([]byte("target"))
+	// mstore(0, call(gas, target, 1, 0, 0, 0, 0)) sstore(0, 1) return(0, 32)
+	caller := thor.BytesToAddress([]byte("caller"))
+	state.SetCode(caller, common.Hex2Bytes("600060006000600060017f"+hex.EncodeToString(thor.BytesToBytes32(target.Bytes()).Bytes())+"5af1600052600160005560206000f3"))
+
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClause(tx.NewClause(&caller), 0, math.MaxUint64, &xenv.TransactionContext{})
+	// caller continues, and the sub-call reports failure
+	assert.Nil(t, out.VMErr)
+	assert.Equal(t, thor.Bytes32{}, thor.BytesToBytes32(out.Data))
+	assert.Equal(t, thor.BytesToBytes32([]byte{1}), state.GetStorage(caller, thor.Bytes32{}))
+	assert.Equal(t, 0, state.GetBalance(target).Sign())
+}
+
+func TestSimulate(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// log0(0, 0) sstore(0, 1)
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	state.SetCode(emitter, common.Hex2Bytes("60006000a0600160005500"))
+	// revert(0, 0)
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	state.SetCode(reverter, common.Hex2Bytes("60006000fd"))
+
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+	root, _ := state.Stage().Hash()
+
+	receipt := rt.Simulate([]*tx.Clause{tx.NewClause(&emitter), tx.NewClause(&emitter)}, 100000, &xenv.TransactionContext{})
+	assert.False(t, receipt.Reverted)
+	assert.Len(t, receipt.Outputs, 2)
+	assert.Len(t, receipt.Logs, 2)
+	assert.Equal(t, receipt.Outputs[0].GasUsed(100000)+receipt.Outputs[1].GasUsed(receipt.Outputs[0].LeftOverGas), receipt.GasUsed)
+
+	receipt = rt.Simulate([]*tx.Clause{tx.NewClause(&emitter), tx.NewClause(&reverter), tx.NewClause(&emitter)}, 100000, &xenv.TransactionContext{})
+	assert.True(t, receipt.Reverted)
+	assert.Len(t, receipt.Outputs, 2)
+	assert.Len(t, receipt.Logs, 0)
+
+	// state unchanged
+	newRoot, _ := state.Stage().Hash()
+	assert.Equal(t, root, newRoot)
+}
+
+func TestFinalize(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, 1) sstore(0, 0)
+	target := thor.BytesToAddress([]byte("target"))
+	state.SetCode(target, common.Hex2Bytes("60016000556000600055"))
+	payer := thor.BytesToAddress([]byte("payer"))
+
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+	out := rt.ExecuteClause(tx.NewClause(&target), 0, 100000, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+
+	// refund is capped to half of used gas
+	gasUsed := out.GasUsed(100000)
+	assert.Equal(t, uint64(15000), out.RefundGas)
+	assert.Equal(t, out.LeftOverGas+gasUsed/2, out.ReturnedGas(100000))
+
+	gasPrice := big.NewInt(10)
+	refunded := rt.Finalize(out, 100000, payer, gasPrice)
+	expected := new(big.Int).Mul(new(big.Int).SetUint64(out.LeftOverGas+gasUsed/2), gasPrice)
+	assert.Equal(t, expected, refunded)
+	assert.Equal(t, expected, state.GetEnergy(payer, 0))
+}
+
+func TestEnforcePause(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// sstore(0, 1)
+	target := thor.BytesToAddress([]byte("target"))
+	state.SetCode(target, common.Hex2Bytes("6001600055"))
+	proto := builtin.Prototype.Native(state)
+
+	exec := func(enforce bool) error {
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{EnforcePause: enforce}).
+			ExecuteClause(tx.NewClause(&target), 0, 100000, &xenv.TransactionContext{}).VMErr
+	}
+
+	proto.SetPaused(target, true)
+	assert.True(t, proto.IsPaused(target))
+	assert.Equal(t, vm.ErrContractPaused, exec(true))
+	assert.Equal(t, thor.Bytes32{}, state.GetStorage(target, thor.Bytes32{}))
+	// not enforced
+	assert.Nil(t, exec(false))
+
+	state.SetStorage(target, thor.Bytes32{}, thor.Bytes32{})
+	proto.SetPaused(target, false)
+	assert.False(t, proto.IsPaused(target))
+	assert.Nil(t, exec(true))
+	assert.Equal(t, thor.BytesToBytes32([]byte{1}), state.GetStorage(target, thor.Bytes32{}))
+}
+
+func TestBaseGasPriceFallback(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// mstore(0, gasprice) return(0, 32)
+	target := thor.BytesToAddress([]byte("target"))
+	state.SetCode(target, common.Hex2Bytes("3a60005260206000f3"))
+	builtin.Params.Native(state).Set(thor.KeyBaseGasPrice, big.NewInt(1000))
+
+	exec := func(txCtx *xenv.TransactionContext, config vm.Config) *big.Int {
+		out := runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(config).
+			ExecuteClause(tx.NewClause(&target), 0, 100000, txCtx)
+		assert.Nil(t, out.VMErr)
+		return new(big.Int).SetBytes(out.Data)
+	}
+
+	assert.Equal(t, big.NewInt(1000), exec(&xenv.TransactionContext{}, vm.Config{}))
+	assert.Equal(t, big.NewInt(2000), exec(&xenv.TransactionContext{GasPrice: big.NewInt(2000)}, vm.Config{}))
+
+	// oracle takes precedence over base gas price
+	oracle := vm.Config{GasOracle: fixedGasOracle{big.NewInt(3000)}}
+	assert.Equal(t, big.NewInt(3000), exec(&xenv.TransactionContext{}, oracle))
+	assert.Equal(t, big.NewInt(2000), exec(&xenv.TransactionContext{GasPrice: big.NewInt(2000)}, oracle))
+}
+
+type fixedGasOracle struct{ price *big.Int }
+
+func (o fixedGasOracle) SuggestGasPrice(vm.Context) *big.Int { return o.price }
+
+func TestRevertSelector(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	exec := func(code string) *runtime.Output {
+		target := thor.BytesToAddress([]byte("target"))
+		state.SetCode(target, common.Hex2Bytes(code))
+		return runtime.New(nil, state, &xenv.BlockContext{}).
+			ExecuteClause(tx.NewClause(&target), 0, 100000, &xenv.TransactionContext{})
+	}
+
+	// mstore(0, selector) mstore(32, 42) revert(28, 36)
+	out := exec("63deadbeef600052602a6020526024601cfd")
+	selector, args, ok := out.RevertSelector()
+	assert.True(t, ok)
+	assert.Equal(t, [4]byte{0xde, 0xad, 0xbe, 0xef}, selector)
+	assert.Equal(t, thor.BytesToBytes32([]byte{42}).Bytes(), args)
+
+	// Error(string)
+	_, _, ok = exec("6308c379a0600052602a6020526024601cfd").RevertSelector()
+	assert.False(t, ok)
+
+	// Panic(uint256)
+	_, _, ok = exec("634e487b71600052602a6020526024601cfd").RevertSelector()
+	assert.False(t, ok)
+
+	// mstore(0, 42) return(0, 32)
+	_, _, ok = exec("602a60005260206000f3").RevertSelector()
+	assert.False(t, ok)
+}
+
+func TestBalanceChanges(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	origin := thor.BytesToAddress([]byte("origin"))
+	target := thor.BytesToAddress([]byte("target"))
+	state.SetBalance(origin, big.NewInt(100))
+
+	out := runtime.New(nil, state, &xenv.BlockContext{}).
+		ExecuteClause(tx.NewClause(&target).WithValue(big.NewInt(30)), 0, 100000, &xenv.TransactionContext{Origin: origin})
+	assert.Nil(t, out.VMErr)
+
+	changes := out.BalanceChanges()
+	assert.Len(t, changes, 2)
+	assert.Equal(t, big.NewInt(-30), changes[origin])
+	assert.Equal(t, big.NewInt(30), changes[target])
+	assert.Equal(t, 0, new(big.Int).Add(changes[origin], changes[target]).Sign())
+}
+
+func TestReplayBlock(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := chain.New(kv, b0)
+
+	txs := tx.Transactions{
+		txSign(txBuilder(ch.Tag()).Clause(clause().WithValue(big.NewInt(1)))),
+		txSign(txBuilder(ch.Tag()).Nonce(2).Clause(clause().WithValue(big.NewInt(2)))),
+	}
+	replay := func() (thor.Bytes32, tx.Receipts) {
+		state, _ := state.New(b0.Header().StateRoot(), kv)
+		root, receipts, err := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{Number: 1, Time: b0.Header().Timestamp() + thor.BlockInterval}).
+			ReplayBlock(txs)
+		assert.Nil(t, err)
+		return root, receipts
+	}
+
+	root, receipts := replay()
+	assert.Len(t, receipts, 2)
+	for _, receipt := range receipts {
+		assert.False(t, receipt.Reverted)
+	}
+	assert.NotEqual(t, b0.Header().StateRoot(), root)
+
+	// deterministic
+	root2, receipts2 := replay()
+	assert.Equal(t, root, root2)
+	assert.Equal(t, receipts, receipts2)
+}
+
+func TestTouchedAddresses(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	inner := thor.BytesToAddress([]byte{0x13, 0x39})
+	outer := thor.BytesToAddress([]byte("outer"))
+	// sstore(0, 1)
+	state.SetCode(inner, common.Hex2Bytes("6001600055"))
+	// call(gas, 0x1339, 0, 0, 0, 0, 0) pop stop
+	state.SetCode(outer, common.Hex2Bytes("600060006000600060006113395af15000"))
+
+	exec := func(track bool) *runtime.Output {
+		out := runtime.New(nil, state, &xenv.BlockContext{}).
+			SetVMConfig(vm.Config{TrackTouched: track}).
+			ExecuteClause(tx.NewClause(&outer), 0, 100000, &xenv.TransactionContext{})
+		assert.Nil(t, out.VMErr)
+		return out
+	}
+
+	assert.Nil(t, exec(false).TouchedAddresses)
+
+	touched := exec(true).TouchedAddresses
+	assert.Contains(t, touched, outer)
+	assert.Contains(t, touched, inner)
+	for i := 1; i < len(touched); i++ {
+		assert.True(t, bytes.Compare(touched[i-1].Bytes(), touched[i].Bytes()) < 0, "should be sorted and deduplicated")
+	}
+}
+
+func TestTransientAcrossClauses(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// with calldata: tstore(0, 42)
+	// without calldata: mstore(0, tload(0)) return(0, 32)
+	addr := thor.BytesToAddress([]byte("transient"))
+	state.SetCode(addr, common.Hex2Bytes("3615600b57602a60005d005b60005c60005260206000f3"))
+
+	// disabled by default
+	rt := runtime.New(nil, state, &xenv.BlockContext{})
+	out := rt.ExecuteClause(tx.NewClause(&addr), 0, 100000, &xenv.TransactionContext{})
+	assert.Equal(t, vm.ErrInvalidOpcode, errors.Cause(out.VMErr))
+
+	rt.SetVMConfig(vm.Config{EIP1153: true})
+	result := rt.ExecuteClauses([]*tx.Clause{
+		tx.NewClause(&addr).WithData([]byte{1}),
+		tx.NewClause(&addr),
+	}, 100000, &xenv.TransactionContext{})
+
+	assert.Len(t, result.Outputs, 2)
+	for _, o := range result.Outputs {
+		assert.Nil(t, o.VMErr)
+	}
+	assert.Equal(t, big.NewInt(42), new(big.Int).SetBytes(result.Outputs[1].Data))
+
+	// discarded after the batch
+	out = rt.ExecuteClause(tx.NewClause(&addr), 0, 100000, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	assert.Equal(t, 0, new(big.Int).SetBytes(out.Data).Sign())
+
+	// not shared by single clauses
+	out = rt.ExecuteClause(tx.NewClause(&addr).WithData([]byte{1}), 0, 100000, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	out = rt.ExecuteClause(tx.NewClause(&addr), 0, 100000, &xenv.TransactionContext{})
+	assert.Nil(t, out.VMErr)
+	assert.Equal(t, 0, new(big.Int).SetBytes(out.Data).Sign())
+}
+
+func TestReadOnly(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	g, _ := genesis.NewDevnet()
+	b0, _, err := g.Build(state.NewCreator(kv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, _ := chain.New(kv, b0)
+	state, _ := state.New(b0.Header().StateRoot(), kv)
+
+	rt := runtime.New(ch.NewSeeker(b0.Header().ID()), state, &xenv.BlockContext{Time: b0.Header().Timestamp()}).
+		SetVMConfig(vm.Config{ReadOnly: true})
+
+	from := genesis.DevAccounts()[0].Address
+	to := thor.BytesToAddress([]byte("to"))
+	transfer, _ := builtin.Energy.ABI.MethodByName("transfer")
+	data, _ := transfer.EncodeInput(to, big.NewInt(1))
+
+	// reaches non-const native method native_sub
+	out := rt.ExecuteClause(tx.NewClause(&builtin.Energy.Address).WithData(data), 0, 1000000, &xenv.TransactionContext{Origin: from})
+	assert.NotNil(t, out.VMErr)
+	assert.Equal(t, 0, state.GetEnergy(to, b0.Header().Timestamp()).Sign())
+
+	balanceOf, _ := builtin.Energy.ABI.MethodByName("balanceOf")
+	data, _ = balanceOf.EncodeInput(from)
+	out = rt.ExecuteClause(tx.NewClause(&builtin.Energy.Address).WithData(data), 0, 1000000, &xenv.TransactionContext{Origin: from})
+	assert.Nil(t, out.VMErr)
+}
+
+func TestNetGasMeteringAcrossClauses(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := state.New(thor.Bytes32{}, kv)
+
+	// with calldata: sstore(0, 1)
+	// without calldata: sstore(0, 0)
+	addr := thor.BytesToAddress([]byte("sstore"))
+	state.SetCode(addr, common.Hex2Bytes("3615600b576001600055005b600060005500"))
+
+	rt := runtime.New(nil, state, &xenv.BlockContext{}).SetVMConfig(vm.Config{EIP2200: true})
+	result := rt.ExecuteClauses([]*tx.Clause{
+		tx.NewClause(&addr).WithData([]byte{1}),
+		tx.NewClause(&addr),
+	}, 100000, &xenv.TransactionContext{})
+
+	assert.Len(t, result.Outputs, 2)
+	for _, o := range result.Outputs {
+		assert.Nil(t, o.VMErr)
+	}
+	// slot reset to the value at the beginning of the transaction
+	assert.Equal(t, params.SstoreSetGas-800, result.Outputs[1].RefundGas)
+}
@@ -6,8 +6,10 @@
 package statedb
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -22,10 +24,16 @@ var codeSizeCache, _ = lru.New(32 * 1024)
 
 // StateDB implements evm.StateDB, only adapt to evm.
 type StateDB struct {
-	state *state.State
-	repo  *stackedmap.StackedMap
+	state     *state.State
+	repo      *stackedmap.StackedMap
+	transient Transient
+	touched   map[thor.Address]bool
 }
 
+// Transient is the transient storage of a transaction, see EIP-1153.
+// It's shared by statedbs of clauses of the transaction.
+type Transient map[transientKey]common.Hash
+
 type (
 	suicideFlagKey common.Address
 	refundKey      struct{}
@@ -33,24 +41,73 @@ type (
 	eventKey       struct{}
 	transferKey    struct{}
 	stateRevKey    struct{}
+	transientKey   struct {
+		addr common.Address
+		key  common.Hash
+	}
 )
 
 // New create a statedb object.
 func New(state *state.State) *StateDB {
+	return NewWithTransient(state, make(Transient))
+}
+
+// NewWithTransient create a statedb object, whose transient storage is initialized from transient.
+// Changes to transient storage are written back by CommitTransient.
+func NewWithTransient(state *state.State, transient Transient) *StateDB {
 	getter := func(k interface{}) (interface{}, bool) {
-		switch k.(type) {
+		switch key := k.(type) {
 		case suicideFlagKey:
 			return false, true
 		case refundKey:
 			return uint64(0), true
+		case transientKey:
+			return transient[key], true
 		}
 		panic(fmt.Sprintf("unknown type of key %+v", k))
 	}
 
 	repo := stackedmap.New(getter)
 	return &StateDB{
-		state,
-		repo,
+		state:     state,
+		repo:      repo,
+		transient: transient,
+	}
+}
+
+// CommitTransient writes changes to transient storage back, excluding the ones within reverted calls.
+func (s *StateDB) CommitTransient() {
+	s.repo.Journal(func(k, v interface{}) bool {
+		if key, ok := k.(transientKey); ok {
+			s.transient[key] = v.(common.Hash)
+		}
+		return true
+	})
+}
+
+// TrackTouched enables recording of addresses read or written, see GetTouched.
+func (s *StateDB) TrackTouched() {
+	if s.touched == nil {
+		s.touched = make(map[thor.Address]bool)
+	}
+}
+
+// GetTouched returns addresses read or written since TrackTouched, sorted.
+// Accesses within reverted calls are included.
+func (s *StateDB) GetTouched() []thor.Address {
+	addrs := make([]thor.Address, 0, len(s.touched))
+	for addr := range s.touched {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+func (s *StateDB) touch(addr common.Address) {
+	if s.touched != nil {
+		s.touched[thor.Address(addr)] = true
 	}
 }
 
@@ -78,6 +135,21 @@ func (s *StateDB) GetLogs() (tx.Events, tx.Transfers) {
 	return events, transfers
 }
 
+// GetSuicided returns addresses of accounts suicided during VM life-cycle.
+// Suicides within reverted calls are excluded.
+func (s *StateDB) GetSuicided() []thor.Address {
+	var addrs []thor.Address
+	seen := make(map[suicideFlagKey]bool)
+	s.repo.Journal(func(k, v interface{}) bool {
+		if key, ok := k.(suicideFlagKey); ok && !seen[key] {
+			seen[key] = true
+			addrs = append(addrs, thor.Address(key))
+		}
+		return true
+	})
+	return addrs
+}
+
 // ForEachStorage see state.State.ForEachStorage.
 // func (s *StateDB) ForEachStorage(addr common.Address, cb func(common.Hash, common.Hash) bool) {
 // 	s.state.ForEachStorage(thor.Address(addr), func(k thor.Bytes32, v []byte) bool {
@@ -91,11 +163,13 @@ func (s *StateDB) CreateAccount(addr common.Address) {}
 
 // GetBalance stub.
 func (s *StateDB) GetBalance(addr common.Address) *big.Int {
+	s.touch(addr)
 	return s.state.GetBalance(thor.Address(addr))
 }
 
 // SubBalance stub.
 func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
+	s.touch(addr)
 	if amount.Sign() == 0 {
 		return
 	}
@@ -105,6 +179,7 @@ func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 
 // AddBalance stub.
 func (s *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	s.touch(addr)
 	if amount.Sign() == 0 {
 		return
 	}
@@ -120,16 +195,19 @@ func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {}
 
 // GetCodeHash stub.
 func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
+	s.touch(addr)
 	return common.Hash(s.state.GetCodeHash(thor.Address(addr)))
 }
 
 // GetCode stub.
 func (s *StateDB) GetCode(addr common.Address) []byte {
+	s.touch(addr)
 	return s.state.GetCode(thor.Address(addr))
 }
 
 // GetCodeSize stub.
 func (s *StateDB) GetCodeSize(addr common.Address) int {
+	s.touch(addr)
 	hash := s.state.GetCodeHash(thor.Address(addr))
 	if hash.IsZero() {
 		return 0
@@ -144,6 +222,7 @@ func (s *StateDB) GetCodeSize(addr common.Address) int {
 
 // SetCode stub.
 func (s *StateDB) SetCode(addr common.Address, code []byte) {
+	s.touch(addr)
 	s.state.SetCode(thor.Address(addr), code)
 }
 
@@ -159,6 +238,7 @@ func (s *StateDB) HasSuicided(addr common.Address) bool {
 // 1, delete account
 // 2, set suicide flag
 func (s *StateDB) Suicide(addr common.Address) bool {
+	s.touch(addr)
 	if !s.state.Exists(thor.Address(addr)) {
 		return false
 	}
@@ -169,21 +249,36 @@ func (s *StateDB) Suicide(addr common.Address) bool {
 
 // GetState stub.
 func (s *StateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	s.touch(addr)
 	return common.Hash(s.state.GetStorage(thor.Address(addr), thor.Bytes32(key)))
 }
 
 // SetState stub.
 func (s *StateDB) SetState(addr common.Address, key, value common.Hash) {
+	s.touch(addr)
 	s.state.SetStorage(thor.Address(addr), thor.Bytes32(key), thor.Bytes32(value))
 }
 
+// GetTransientState stub.
+func (s *StateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	v, _ := s.repo.Get(transientKey{addr, key})
+	return v.(common.Hash)
+}
+
+// SetTransientState stub.
+func (s *StateDB) SetTransientState(addr common.Address, key, value common.Hash) {
+	s.repo.Put(transientKey{addr, key}, value)
+}
+
 // Exist stub.
 func (s *StateDB) Exist(addr common.Address) bool {
+	s.touch(addr)
 	return s.state.Exists(thor.Address(addr))
 }
 
 // Empty stub.
 func (s *StateDB) Empty(addr common.Address) bool {
+	s.touch(addr)
 	return !s.state.Exists(thor.Address(addr))
 }
 
@@ -194,6 +289,16 @@ func (s *StateDB) AddRefund(gas uint64) {
 	s.repo.Put(refundKey{}, total)
 }
 
+// SubRefund subtracts gas from refund, which is floored at zero.
+func (s *StateDB) SubRefund(gas uint64) {
+	v, _ := s.repo.Get(refundKey{})
+	total := v.(uint64)
+	if gas > total {
+		gas = total
+	}
+	s.repo.Put(refundKey{}, total-gas)
+}
+
 // AddPreimage stub.
 func (s *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
 	s.repo.Put(preimageKey(hash), preimage)
@@ -0,0 +1,81 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/vechain/thor/thor"
+)
+
+// StateDelta is the set of differences between two states.
+type StateDelta struct {
+	// addresses with different balance, energy, master or code
+	Accounts []thor.Address
+	// storage keys with different values, grouped by address
+	Storage map[thor.Address][]thor.Bytes32
+}
+
+// IsEmpty returns whether there's no difference.
+func (d *StateDelta) IsEmpty() bool {
+	return len(d.Accounts) == 0 && len(d.Storage) == 0
+}
+
+// Diff compares two states and returns the differences.
+// Only entries touched in either state are compared, so both states are
+// expected to be derived from the same root.
+func Diff(a, b *State) *StateDelta {
+	var (
+		addrs = make(map[thor.Address]bool)
+		keys  = make(map[storageKey]bool)
+	)
+	collect := func(k, v interface{}) bool {
+		switch key := k.(type) {
+		case thor.Address:
+			addrs[key] = true
+		case codeKey:
+			addrs[thor.Address(key)] = true
+		case storageKey:
+			keys[key] = true
+		}
+		return true
+	}
+	a.sm.Journal(collect)
+	b.sm.Journal(collect)
+
+	delta := &StateDelta{Storage: make(map[thor.Address][]thor.Bytes32)}
+	for addr := range addrs {
+		if !accountEqual(a.getAccount(addr), b.getAccount(addr)) {
+			delta.Accounts = append(delta.Accounts, addr)
+		}
+	}
+	for key := range keys {
+		if !bytes.Equal(a.GetRawStorage(key.addr, key.key), b.GetRawStorage(key.addr, key.key)) {
+			delta.Storage[key.addr] = append(delta.Storage[key.addr], key.key)
+		}
+	}
+
+	sort.Slice(delta.Accounts, func(i, j int) bool {
+		return bytes.Compare(delta.Accounts[i][:], delta.Accounts[j][:]) < 0
+	})
+	for _, keys := range delta.Storage {
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i][:], keys[j][:]) < 0
+		})
+	}
+	return delta
+}
+
+// accountEqual compares accounts except the storage root, which is not
+// updated until the state is staged.
+func accountEqual(a, b *Account) bool {
+	return a.Balance.Cmp(b.Balance) == 0 &&
+		a.Energy.Cmp(b.Energy) == 0 &&
+		a.BlockTime == b.BlockTime &&
+		bytes.Equal(a.Master, b.Master) &&
+		bytes.Equal(a.CodeHash, b.CodeHash)
+}
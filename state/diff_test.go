@@ -0,0 +1,49 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/thor"
+)
+
+func TestDiff(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+
+	addr1 := thor.BytesToAddress([]byte("account1"))
+	addr2 := thor.BytesToAddress([]byte("account2"))
+	addr3 := thor.BytesToAddress([]byte("account3"))
+	key1 := thor.BytesToBytes32([]byte("key1"))
+	key2 := thor.BytesToBytes32([]byte("key2"))
+
+	base, _ := New(thor.Bytes32{}, kv)
+	base.SetBalance(addr1, big.NewInt(1))
+	base.SetStorage(addr1, key1, thor.BytesToBytes32([]byte("v1")))
+	root, _ := base.Stage().Commit()
+
+	a, _ := New(root, kv)
+	b, _ := New(root, kv)
+	assert.True(t, Diff(a, b).IsEmpty())
+
+	// same changes on both sides are not reported
+	a.SetBalance(addr2, big.NewInt(2))
+	b.SetBalance(addr2, big.NewInt(2))
+	assert.True(t, Diff(a, b).IsEmpty())
+
+	b.SetBalance(addr1, big.NewInt(10))
+	b.SetCode(addr3, []byte("code"))
+	b.SetStorage(addr1, key1, thor.BytesToBytes32([]byte("v2")))
+	b.SetStorage(addr1, key2, thor.Bytes32{})
+
+	delta := Diff(a, b)
+	assert.Equal(t, []thor.Address{addr1, addr3}, delta.Accounts)
+	assert.Equal(t, map[thor.Address][]thor.Bytes32{addr1: {key1}}, delta.Storage)
+	assert.Equal(t, delta, Diff(b, a))
+}
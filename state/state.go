@@ -216,6 +216,16 @@ func (s *State) GetBalance(addr thor.Address) *big.Int {
 	return s.getAccount(addr).Balance
 }
 
+// GetEachBalance returns balances of the given addresses, in the same order.
+// It's a convenience wrapper looking up each account as GetBalance does, without batched reads.
+func (s *State) GetEachBalance(addrs []thor.Address) []*big.Int {
+	balances := make([]*big.Int, len(addrs))
+	for i, addr := range addrs {
+		balances[i] = s.GetBalance(addr)
+	}
+	return balances
+}
+
 // SetBalance set balance for the given address.
 func (s *State) SetBalance(addr thor.Address, balance *big.Int) {
 	cpy := s.getAccountCopy(addr)
@@ -228,6 +238,11 @@ func (s *State) GetEnergy(addr thor.Address, blockTime uint64) *big.Int {
 	return s.getAccount(addr).CalcEnergy(blockTime)
 }
 
+// GetEnergyBlockTime returns the block time at which energy of the given address was last updated.
+func (s *State) GetEnergyBlockTime(addr thor.Address) uint64 {
+	return s.getAccount(addr).BlockTime
+}
+
 // SetEnergy set energy at block number for the given address.
 func (s *State) SetEnergy(addr thor.Address, energy *big.Int, blockTime uint64) {
 	cpy := s.getAccountCopy(addr)
@@ -364,6 +379,44 @@ func (s *State) Delete(addr thor.Address) {
 	s.updateAccount(addr, emptyAccount())
 }
 
+// Reap deletes accounts at the given addresses which are left empty and hold no storage.
+// It returns addresses of deleted accounts.
+func (s *State) Reap(addrs []thor.Address) []thor.Address {
+	var candidates []thor.Address
+	for _, addr := range addrs {
+		if a := s.getAccount(addr); a.IsEmpty() && len(a.StorageRoot) == 0 {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// storage not yet committed
+	storage := make(map[storageKey][]byte)
+	s.sm.Journal(func(k, v interface{}) bool {
+		if key, ok := k.(storageKey); ok {
+			storage[key] = v.([]byte)
+		}
+		return true
+	})
+	holding := make(map[thor.Address]bool)
+	for key, value := range storage {
+		if len(value) > 0 {
+			holding[key.addr] = true
+		}
+	}
+
+	var reaped []thor.Address
+	for _, addr := range candidates {
+		if !holding[addr] {
+			s.Delete(addr)
+			reaped = append(reaped, addr)
+		}
+	}
+	return reaped
+}
+
 // NewCheckpoint makes a checkpoint of current state.
 // It returns revision of the checkpoint.
 func (s *State) NewCheckpoint() int {
@@ -375,6 +428,35 @@ func (s *State) RevertTo(revision int) {
 	s.sm.PopTo(revision)
 }
 
+// SetupGenesis sets balances of given accounts as if they were allocated in genesis.
+// The balances are committed into the accounts trie, which becomes the initial trie of the state,
+// so they are not tracked as changes. Pending changes of the same accounts take precedence.
+func (s *State) SetupGenesis(allocs map[thor.Address]*big.Int) {
+	if s.err != nil {
+		return
+	}
+	changes := make(map[thor.Address]*changedObject, len(allocs))
+	for addr, balance := range allocs {
+		data := s.getCachedObject(addr).data
+		data.Balance = balance
+		changes[addr] = &changedObject{data: data}
+	}
+	root, err := newStage(s.root, s.kv, changes).Commit()
+	if err != nil {
+		s.setError(err)
+		return
+	}
+	trie, err := trCache.Get(root, s.kv, false)
+	if err != nil {
+		s.setError(err)
+		return
+	}
+	s.root, s.trie = root, trie
+	for addr := range allocs {
+		delete(s.cache, addr)
+	}
+}
+
 // Stage makes a stage object to compute hash of trie or commit all changes.
 func (s *State) Stage() *Stage {
 	if s.err != nil {
@@ -387,6 +469,25 @@ func (s *State) Stage() *Stage {
 	return newStage(s.root, s.kv, changes)
 }
 
+// CommitOnly commits changes of the given accounts only, and returns the new root of the accounts trie.
+// Changes of other accounts are not persisted.
+func (s *State) CommitOnly(addrs []thor.Address) (thor.Bytes32, error) {
+	if s.err != nil {
+		return thor.Bytes32{}, s.err
+	}
+	changes := s.changes()
+	if s.err != nil {
+		return thor.Bytes32{}, s.err
+	}
+	selected := make(map[thor.Address]*changedObject, len(addrs))
+	for _, addr := range addrs {
+		if obj, ok := changes[addr]; ok {
+			selected[addr] = obj
+		}
+	}
+	return newStage(s.root, s.kv, selected).Commit()
+}
+
 type (
 	storageKey struct {
 		addr thor.Address
@@ -121,3 +121,132 @@ func TestEnergy(t *testing.T) {
 
 	assert.Equal(t, x, bal1)
 }
+
+func TestSetupGenesis(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := New(thor.Bytes32{}, kv)
+
+	addr1 := thor.BytesToAddress([]byte("addr1"))
+	addr2 := thor.BytesToAddress([]byte("addr2"))
+	addr3 := thor.BytesToAddress([]byte("addr3"))
+
+	state.SetBalance(addr3, big.NewInt(3))
+	state.SetupGenesis(map[thor.Address]*big.Int{
+		addr1: big.NewInt(1),
+		addr2: big.NewInt(2),
+	})
+	assert.Nil(t, state.Err())
+
+	assert.Equal(t, big.NewInt(1), state.GetBalance(addr1))
+	assert.Equal(t, big.NewInt(2), state.GetBalance(addr2))
+	assert.Equal(t, big.NewInt(3), state.GetBalance(addr3))
+
+	// only the pending change is tracked
+	changes := state.changes()
+	assert.Len(t, changes, 1)
+	assert.NotNil(t, changes[addr3])
+
+	// pre-funded balances persist
+	root, _ := state.Stage().Commit()
+	state, _ = New(root, kv)
+	assert.Equal(t, big.NewInt(1), state.GetBalance(addr1))
+	assert.Equal(t, big.NewInt(3), state.GetBalance(addr3))
+}
+
+func TestGetEachBalance(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := New(thor.Bytes32{}, kv)
+
+	allocs := make(map[thor.Address]*big.Int)
+	var addrs []thor.Address
+	for i := 1; i <= 50; i++ {
+		addr := thor.BytesToAddress([]byte{byte(i)})
+		allocs[addr] = big.NewInt(int64(i * 100))
+		addrs = append(addrs, addr)
+	}
+	state.SetupGenesis(allocs)
+
+	// pending change and unknown account
+	state.SetBalance(addrs[0], big.NewInt(1))
+	addrs = append(addrs, thor.BytesToAddress([]byte("unknown")))
+
+	balances := state.GetEachBalance(addrs)
+	assert.Len(t, balances, len(addrs))
+	assert.Equal(t, big.NewInt(1), balances[0])
+	for i := 1; i < 50; i++ {
+		assert.Equal(t, allocs[addrs[i]], balances[i])
+	}
+	assert.Equal(t, 0, balances[50].Sign())
+}
+
+func BenchmarkGetEachBalance(b *testing.B) {
+	kv, _ := lvldb.NewMem()
+	st, _ := New(thor.Bytes32{}, kv)
+
+	allocs := make(map[thor.Address]*big.Int)
+	var addrs []thor.Address
+	for i := 1; i <= 50; i++ {
+		addr := thor.BytesToAddress([]byte{byte(i)})
+		allocs[addr] = big.NewInt(int64(i))
+		addrs = append(addrs, addr)
+	}
+	st.SetupGenesis(allocs)
+	root := st.root
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st, _ := New(root, kv)
+		st.GetEachBalance(addrs)
+	}
+}
+
+func TestCommitOnly(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := New(thor.Bytes32{}, kv)
+
+	addr1 := thor.BytesToAddress([]byte("addr1"))
+	addr2 := thor.BytesToAddress([]byte("addr2"))
+	addr3 := thor.BytesToAddress([]byte("addr3"))
+	key := thor.BytesToBytes32([]byte("key"))
+
+	state.SetBalance(addr1, big.NewInt(1))
+	state.SetStorage(addr2, key, thor.BytesToBytes32([]byte("value")))
+	state.SetBalance(addr2, big.NewInt(2))
+	state.SetBalance(addr3, big.NewInt(3))
+	state.SetStorage(addr3, key, thor.BytesToBytes32([]byte("value")))
+
+	root, err := state.CommitOnly([]thor.Address{addr1, addr2})
+	assert.Nil(t, err)
+
+	state, _ = New(root, kv)
+	assert.Equal(t, big.NewInt(1), state.GetBalance(addr1))
+	assert.Equal(t, big.NewInt(2), state.GetBalance(addr2))
+	assert.Equal(t, thor.BytesToBytes32([]byte("value")), state.GetStorage(addr2, key))
+	assert.Equal(t, 0, state.GetBalance(addr3).Sign())
+	assert.Equal(t, thor.Bytes32{}, state.GetStorage(addr3, key))
+}
+
+func TestReap(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	state, _ := New(thor.Bytes32{}, kv)
+
+	addr1 := thor.BytesToAddress([]byte("addr1"))
+	addr2 := thor.BytesToAddress([]byte("addr2"))
+	addr3 := thor.BytesToAddress([]byte("addr3"))
+	key := thor.BytesToBytes32([]byte("key"))
+
+	state.SetBalance(addr1, big.NewInt(1))
+	state.SetStorage(addr2, key, thor.BytesToBytes32([]byte("value")))
+	state.SetEnergy(addr3, big.NewInt(1), 10)
+	state.SetEnergy(addr3, &big.Int{}, 10)
+
+	assert.Equal(t, []thor.Address{addr3}, state.Reap([]thor.Address{addr1, addr2, addr3}))
+	assert.Equal(t, big.NewInt(1), state.GetBalance(addr1))
+	// storage kept
+	assert.Equal(t, thor.BytesToBytes32([]byte("value")), state.GetStorage(addr2, key))
+	assert.Equal(t, uint64(0), state.GetEnergyBlockTime(addr3))
+
+	// reapable once storage cleared
+	state.SetStorage(addr2, key, thor.Bytes32{})
+	assert.Equal(t, []thor.Address{addr2}, state.Reap([]thor.Address{addr1, addr2}))
+}
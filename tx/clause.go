@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/vechain/thor/thor"
@@ -106,3 +107,44 @@ func (c *Clause) String() string {
 		 Value:	%v
 		 Data:	0x%x)`, to, c.body.Value, c.body.Data)
 }
+
+// ClausesHash computes a stable hash over the clause set along with the chain tag, gas and nonce.
+func ClausesHash(chainTag byte, clauses []*Clause, gas uint64, nonce uint64) (hash thor.Bytes32) {
+	hw := thor.NewBlake2b()
+	rlp.Encode(hw, []interface{}{
+		chainTag,
+		clauses,
+		gas,
+		nonce,
+	})
+	hw.Sum(hash[:0])
+	return
+}
+
+// PackClauses greedily selects clauses, in ascending order of intrinsic gas, until gasLimit is reached.
+// It returns indices of selected clauses in selection order, and their total intrinsic gas.
+// thor.TxGas is not included, and clauses whose intrinsic gas overflows are skipped.
+func PackClauses(clauses []*Clause, gasLimit uint64) (selected []int, totalGas uint64) {
+	type candidate struct {
+		index int
+		gas   uint64
+	}
+	candidates := make([]candidate, 0, len(clauses))
+	for i, c := range clauses {
+		if gas, err := clauseGas(c, DefaultGasConfig); err == nil {
+			candidates = append(candidates, candidate{i, gas})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].gas < candidates[j].gas
+	})
+
+	for _, c := range candidates {
+		if c.gas > gasLimit-totalGas {
+			break
+		}
+		selected = append(selected, c.index)
+		totalGas += c.gas
+	}
+	return
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package tx_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
+)
+
+func TestClausesHash(t *testing.T) {
+	to, _ := thor.ParseAddress("0x7567d83b7b8d80addcb281a71d54fc7b3364ffed")
+	clauses := func() []*tx.Clause {
+		return []*tx.Clause{
+			tx.NewClause(&to).WithValue(big.NewInt(10000)),
+			tx.NewClause(&to).WithData([]byte{0x60, 0x60}),
+		}
+	}
+	hash := tx.ClausesHash(1, clauses(), 21000, 1)
+	assert.Equal(t, "0x4f713ab551aa90e336b34b9c198df98482358c81aad9b0a0bdb5437479ebcecb", hash.String())
+	assert.Equal(t, hash, tx.ClausesHash(1, clauses(), 21000, 1))
+
+	changed := [][]*tx.Clause{
+		{clauses()[0].WithValue(big.NewInt(10001)), clauses()[1]},
+		{clauses()[0], clauses()[1].WithData([]byte{0x60})},
+		{clauses()[0], tx.NewClause(nil).WithData([]byte{0x60, 0x60})},
+		{clauses()[1], clauses()[0]},
+		{clauses()[0]},
+	}
+	for _, c := range changed {
+		assert.NotEqual(t, hash, tx.ClausesHash(1, c, 21000, 1))
+	}
+
+	assert.NotEqual(t, hash, tx.ClausesHash(2, clauses(), 21000, 1))
+	assert.NotEqual(t, hash, tx.ClausesHash(1, clauses(), 21001, 1))
+	assert.NotEqual(t, hash, tx.ClausesHash(1, clauses(), 21000, 2))
+}
+
+func TestPackClauses(t *testing.T) {
+	to, _ := thor.ParseAddress("0x7567d83b7b8d80addcb281a71d54fc7b3364ffed")
+	clauses := []*tx.Clause{
+		tx.NewClause(nil), // 48000
+		tx.NewClause(&to), // 16000
+		tx.NewClause(&to).WithData([]byte{1, 0, 1}), // 16000 + 68*2 + 4
+		tx.NewClause(&to).WithData([]byte{0}),       // 16000 + 4
+	}
+	gases := []uint64{
+		thor.ClauseGasContractCreation,
+		thor.ClauseGas,
+		thor.ClauseGas + 68*2 + 4,
+		thor.ClauseGas + 4,
+	}
+
+	tests := []struct {
+		gasLimit uint64
+		selected []int
+	}{
+		{0, nil},
+		{thor.ClauseGas - 1, nil},
+		{thor.ClauseGas, []int{1}},
+		{thor.ClauseGas*2 + 4, []int{1, 3}},
+		{thor.ClauseGas*3 + 68*2 + 8, []int{1, 3, 2}},
+		{thor.ClauseGas*3 + 68*2 + 8 + thor.ClauseGasContractCreation - 1, []int{1, 3, 2}},
+		{math.MaxUint64, []int{1, 3, 2, 0}},
+	}
+	for _, test := range tests {
+		selected, totalGas := tx.PackClauses(clauses, test.gasLimit)
+		assert.Equal(t, test.selected, selected, "gas limit %d", test.gasLimit)
+
+		var expected uint64
+		for _, i := range selected {
+			expected += gases[i]
+		}
+		assert.Equal(t, expected, totalGas)
+		assert.True(t, totalGas <= test.gasLimit)
+	}
+
+	// consistent with intrinsic gas of tx
+	trx := new(tx.Builder).Clause(clauses[0]).Clause(clauses[2]).Build()
+	intrinsicGas, _ := trx.IntrinsicGas()
+	assert.Equal(t, thor.TxGas+gases[0]+gases[2], intrinsicGas)
+}
+
+func TestClauseRLP(t *testing.T) {
+	to, _ := thor.ParseAddress("0x7567d83b7b8d80addcb281a71d54fc7b3364ffed")
+	for _, c := range []*tx.Clause{
+		tx.NewClause(&to).WithValue(big.NewInt(10000)).WithData([]byte{0x60, 0x60}),
+		tx.NewClause(nil).WithData([]byte{0x60, 0x60}),
+	} {
+		data, err := rlp.EncodeToBytes(c)
+		assert.Nil(t, err)
+
+		var decoded tx.Clause
+		assert.Nil(t, rlp.DecodeBytes(data, &decoded))
+		assert.Equal(t, c.To(), decoded.To())
+		assert.Equal(t, c.Value(), decoded.Value())
+		assert.Equal(t, c.Data(), decoded.Data())
+		assert.Equal(t, c.IsCreatingContract(), decoded.IsCreatingContract())
+	}
+}
@@ -263,20 +263,44 @@ func (t *Transaction) Size() metric.StorageSize {
 	return size
 }
 
+// GasConfig defines per-byte gas costs of clause data.
+type GasConfig struct {
+	TxDataZeroGas    uint64
+	TxDataNonZeroGas uint64
+}
+
+var (
+	// DefaultGasConfig is the cost schedule used by IntrinsicGas.
+	DefaultGasConfig = GasConfig{params.TxDataZeroGas, params.TxDataNonZeroGas}
+	// IstanbulGasConfig is the cost schedule with non-zero byte gas lowered by EIP-2028.
+	IstanbulGasConfig = GasConfig{params.TxDataZeroGas, 16}
+)
+
 // IntrinsicGas returns intrinsic gas of tx.
 func (t *Transaction) IntrinsicGas() (uint64, error) {
-	if len(t.body.Clauses) == 0 {
-		return thor.TxGas + thor.ClauseGas, nil
-	}
-
 	if cached := t.cache.intrinsicGas.Load(); cached != nil {
 		return cached.(uint64), nil
 	}
 
+	total, err := t.IntrinsicGasWithConfig(DefaultGasConfig)
+	if err != nil {
+		return 0, err
+	}
+	t.cache.intrinsicGas.Store(total)
+	return total, nil
+}
+
+// IntrinsicGasWithConfig returns intrinsic gas of tx, with data gas computed
+// by the given cost schedule.
+func (t *Transaction) IntrinsicGasWithConfig(config GasConfig) (uint64, error) {
+	if len(t.body.Clauses) == 0 {
+		return thor.TxGas + thor.ClauseGas, nil
+	}
+
 	var total = thor.TxGas
 	var overflow bool
 	for _, c := range t.body.Clauses {
-		gas, err := dataGas(c.body.Data)
+		gas, err := clauseGas(c, config)
 		if err != nil {
 			return 0, err
 		}
@@ -284,22 +308,30 @@ func (t *Transaction) IntrinsicGas() (uint64, error) {
 		if overflow {
 			return 0, errIntrinsicGasOverflow
 		}
+	}
+	return total, nil
+}
 
-		var cgas uint64
-		if c.IsCreatingContract() {
-			// contract creation
-			cgas = thor.ClauseGasContractCreation
-		} else {
-			cgas = thor.ClauseGas
-		}
+// clauseGas returns intrinsic gas of a single clause, excluding thor.TxGas.
+func clauseGas(c *Clause, config GasConfig) (uint64, error) {
+	gas, err := dataGas(c.body.Data, config)
+	if err != nil {
+		return 0, err
+	}
 
-		total, overflow = math.SafeAdd(total, cgas)
-		if overflow {
-			return 0, errIntrinsicGasOverflow
-		}
+	var cgas uint64
+	if c.IsCreatingContract() {
+		// contract creation
+		cgas = thor.ClauseGasContractCreation
+	} else {
+		cgas = thor.ClauseGas
 	}
-	t.cache.intrinsicGas.Store(total)
-	return total, nil
+
+	gas, overflow := math.SafeAdd(gas, cgas)
+	if overflow {
+		return 0, errIntrinsicGasOverflow
+	}
+	return gas, nil
 }
 
 // GasPrice returns gas price.
@@ -394,7 +426,7 @@ func (t *Transaction) String() string {
 }
 
 // see core.IntrinsicGas
-func dataGas(data []byte) (uint64, error) {
+func dataGas(data []byte, config GasConfig) (uint64, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
@@ -406,11 +438,11 @@ func dataGas(data []byte) (uint64, error) {
 			nz++
 		}
 	}
-	zgas, overflow := math.SafeMul(params.TxDataZeroGas, z)
+	zgas, overflow := math.SafeMul(config.TxDataZeroGas, z)
 	if overflow {
 		return 0, errIntrinsicGasOverflow
 	}
-	nzgas, overflow := math.SafeMul(params.TxDataNonZeroGas, nz)
+	nzgas, overflow := math.SafeMul(config.TxDataNonZeroGas, nz)
 	if overflow {
 		return 0, errIntrinsicGasOverflow
 	}
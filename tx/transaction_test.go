@@ -51,6 +51,24 @@ func TestTx(t *testing.T) {
 	)
 }
 
+func TestIntrinsicGasWithConfig(t *testing.T) {
+	to, _ := thor.ParseAddress("0x7567d83b7b8d80addcb281a71d54fc7b3364ffed")
+	trx := new(tx.Builder).
+		Clause(tx.NewClause(&to).WithData([]byte{0, 0, 0, 0x60, 0x60, 0x60})).
+		Clause(tx.NewClause(&to).WithData([]byte{0, 0, 0, 0x60, 0x60, 0x60})).
+		Build()
+
+	def, err := trx.IntrinsicGasWithConfig(tx.DefaultGasConfig)
+	assert.Nil(t, err)
+	istanbul, err := trx.IntrinsicGasWithConfig(tx.IstanbulGasConfig)
+	assert.Nil(t, err)
+
+	assert.Equal(t, uint64(37432), def)
+	assert.Equal(t, func() uint64 { g, _ := trx.IntrinsicGas(); return g }(), def)
+	// 6 non-zero bytes, each cheaper by 68 - 16
+	assert.Equal(t, uint64(6*(68-16)), def-istanbul)
+}
+
 func BenchmarkTxMining(b *testing.B) {
 	tx := new(tx.Builder).Build()
 	signer := thor.BytesToAddress([]byte("acc1"))
@@ -11,7 +11,6 @@ import (
 	"github.com/inconshreveable/log15"
 	"github.com/vechain/thor/block"
 	"github.com/vechain/thor/builtin"
-	"github.com/vechain/thor/thor"
 )
 
 func (pool *TxPool) updateLoop() {
@@ -46,7 +45,7 @@ func (pool *TxPool) updateData(bestBlock *block.Block) {
 		return
 	}
 
-	baseGasPrice := builtin.Params.Native(st).Get(thor.KeyBaseGasPrice)
+	baseGasPrice := builtin.Params.Native(st).BaseGasPrice()
 	bestBlockNum := bestBlock.Header().Number()
 	bestBlockID := bestBlock.Header().ID()
 
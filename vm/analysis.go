@@ -20,6 +20,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 )
 
 // destinations stores one map per contract (keyed by hash of code).
@@ -88,3 +89,109 @@ func codeBitmap(code []byte) bitvec {
 	}
 	return bits
 }
+
+// WorstCaseGas statically estimates gas of a single dispatch to the code deployed at addr,
+// by summing costs of all instructions as if each is executed once, with every
+// stack operand set to 1 against an empty state.
+// It's an estimate rather than a bound: loops are not accounted, and dynamic costs
+// such as memory expansion and data copy are estimated by minimal operands.
+// Neither the EVM nor its state is modified.
+func (evm *EVM) WorstCaseGas(addr common.Address) (uint64, error) {
+	code := evm.StateDB.GetCode(addr)
+	contract := NewContract(AccountRef(addr), AccountRef(addr), new(big.Int), math.MaxUint64)
+	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), code)
+
+	// gas functions may record into the EVM and the state, so they're evaluated by a scratch EVM.
+	scratch := &EVM{
+		Context:     evm.Context,
+		StateDB:     NoopStateDB{},
+		chainConfig: evm.chainConfig,
+		chainRules:  evm.chainRules,
+		vmConfig:    evm.vmConfig,
+		interpreter: evm.interpreter,
+	}
+
+	var total uint64
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		op := OpCode(code[pc])
+		if op >= PUSH1 && op <= PUSH32 {
+			pc += uint64(op - PUSH1 + 1)
+		}
+		operation := evm.interpreter.cfg.JumpTable[op]
+		if !operation.valid {
+			continue
+		}
+
+		// the most operands taken by an instruction is 7, by CALL
+		stack := newstack()
+		for i := 0; i < 7; i++ {
+			stack.push(big.NewInt(1))
+		}
+		var memorySize uint64
+		if operation.memorySize != nil {
+			memorySize = toWordSize(operation.memorySize(stack).Uint64()) * 32
+		}
+		cost, err := operation.gasCost(evm.interpreter.gasTable, scratch, contract, stack, NewMemory(), memorySize)
+		if err != nil {
+			return 0, err
+		}
+		var overflow bool
+		if total, overflow = math.SafeAdd(total, cost); overflow {
+			return 0, errGasUintOverflow
+		}
+	}
+	return total, nil
+}
+
+// dispatchSelectors scans code for the function dispatch table generated by
+// solidity, and returns the likely function selectors in order of occurrence.
+// A selector is recognized by the pattern PUSH4 selector, EQ, PUSHn dest, JUMPI,
+// where a DUPn may precede EQ.
+func dispatchSelectors(code []byte) [][4]byte {
+	type instruction struct {
+		op  OpCode
+		arg []byte
+	}
+	var instructions []instruction
+	for pc := 0; pc < len(code); {
+		op := OpCode(code[pc])
+		pc++
+		if op >= PUSH1 && op <= PUSH32 {
+			end := pc + int(op-PUSH1+1)
+			if end > len(code) {
+				break
+			}
+			instructions = append(instructions, instruction{op, code[pc:end]})
+			pc = end
+		} else {
+			instructions = append(instructions, instruction{op, nil})
+		}
+	}
+
+	var (
+		selectors [][4]byte
+		seen      = make(map[[4]byte]bool)
+	)
+	for i, ins := range instructions {
+		if ins.op != PUSH4 {
+			continue
+		}
+		j := i + 1
+		if j < len(instructions) && instructions[j].op >= DUP1 && instructions[j].op <= DUP16 {
+			j++
+		}
+		if j+2 >= len(instructions) ||
+			instructions[j].op != EQ ||
+			instructions[j+1].op < PUSH1 || instructions[j+1].op > PUSH4 ||
+			instructions[j+2].op != JUMPI {
+			continue
+		}
+		var selector [4]byte
+		copy(selector[:], ins.arg)
+		if !seen[selector] {
+			seen[selector] = true
+			selectors = append(selectors, selector)
+		}
+	}
+	return selectors
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// benchStateDB serves the same code for every address.
+type benchStateDB struct {
+	NoopStateDB
+	code []byte
+}
+
+func (db benchStateDB) Exist(common.Address) bool     { return true }
+func (db benchStateDB) GetCode(common.Address) []byte { return db.code }
+
+// BenchmarkCall calls code with input repeatedly, and reports the throughput in calls per second.
+// It's a helper for benchmarking the interpreter, and stops at the first failed call.
+func BenchmarkCall(code []byte, input []byte, iterations int) (opsPerSec float64, err error) {
+	var (
+		ctx = Context{
+			CanTransfer: NoopCanTransfer,
+			Transfer:    NoopTransfer,
+			BlockNumber: new(big.Int),
+		}
+		evm    = NewEVM(ctx, benchStateDB{code: code}, params.TestChainConfig, Config{})
+		caller = AccountRef(common.HexToAddress("1337"))
+		addr   = common.HexToAddress("1338")
+	)
+
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		if _, _, err := evm.Call(caller, addr, input, 10000000, new(big.Int)); err != nil {
+			return 0, err
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+	if elapsed == 0 {
+		return 0, nil
+	}
+	return float64(iterations) / elapsed, nil
+}
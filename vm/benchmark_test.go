@@ -0,0 +1,31 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBenchmarkCall(t *testing.T) {
+	// mstore(0, calldataload(0)) return(0, 32)
+	code := common.Hex2Bytes("60003560005260206000f3")
+	input := common.LeftPadBytes([]byte{42}, 32)
+
+	rate, err := BenchmarkCall(code, input, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate <= 0 {
+		t.Errorf("expected positive rate, got %v", rate)
+	}
+
+	// INVALID
+	if _, err := BenchmarkCall([]byte{0xfe}, nil, 1); err == nil {
+		t.Errorf("expected error for failed call")
+	}
+}
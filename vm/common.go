@@ -21,6 +21,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // calculates the memory size required for a step
@@ -79,3 +80,21 @@ func allZero(b []byte) bool {
 	}
 	return true
 }
+
+// Selector returns the 4-byte function selector of the given signature,
+// e.g. "transfer(address,uint256)".
+func Selector(signature string) (selector [4]byte) {
+	copy(selector[:], crypto.Keccak256([]byte(signature)))
+	return
+}
+
+// EncodeCall builds call input from the selector and arguments. Each argument
+// is left padded to a 32 bytes word.
+func EncodeCall(selector [4]byte, args ...[]byte) []byte {
+	input := make([]byte, 0, 4+32*len(args))
+	input = append(input, selector[:]...)
+	for _, arg := range args {
+		input = append(input, common.LeftPadBytes(arg, 32)...)
+	}
+	return input
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+func TestEncodeCall(t *testing.T) {
+	def, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"Left","inputs":[{"name":"x","type":"int256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := Selector("Left(int256)")
+	if !bytes.Equal(selector[:], def.Methods["Left"].Id()) {
+		t.Errorf("selector mismatch: expected %x, got %x", def.Methods["Left"].Id(), selector)
+	}
+
+	for _, x := range []*big.Int{big.NewInt(0), big.NewInt(5), big.NewInt(-5)} {
+		expected, err := def.Pack("Left", x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := EncodeCall(selector, math.PaddedBigBytes(math.U256(new(big.Int).Set(x)), 32))
+		if !bytes.Equal(input, expected) {
+			t.Errorf("Left(%v): expected %x, got %x", x, expected, input)
+		}
+	}
+
+	if input := EncodeCall(selector, common.Hex2Bytes("01")); len(input) != 36 || input[35] != 1 {
+		t.Errorf("expected argument padded to a word, got %x", input)
+	}
+}
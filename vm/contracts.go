@@ -32,9 +32,11 @@ import (
 // PrecompiledContract is the basic interface for native Go contracts. The implementation
 // requires a deterministic gas count based on the input size of the Run method of the
 // contract.
+// Run receives the gas left after the required gas is charged, and returns the unused
+// gas, so that the contract can consume or forward extra gas.
 type PrecompiledContract interface {
-	RequiredGas(input []byte) uint64  // RequiredPrice calculates the contract gas use
-	Run(input []byte) ([]byte, error) // Run runs the precompiled contract
+	RequiredGas(input []byte) uint64                      // RequiredPrice calculates the contract gas use
+	Run(input []byte, gas uint64) ([]byte, uint64, error) // Run runs the precompiled contract
 }
 
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
@@ -63,7 +65,14 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
 	if contract.UseGas(gas) {
-		return p.Run(input)
+		ret, leftOverGas, err := p.Run(input, contract.Gas)
+		if leftOverGas > contract.Gas {
+			// never create gas out of nothing
+			contract.Gas = 0
+			return nil, ErrOutOfGas
+		}
+		contract.Gas = leftOverGas
+		return ret, err
 	}
 	return nil, ErrOutOfGas
 }
@@ -75,7 +84,7 @@ func (c *ecrecover) RequiredGas(input []byte) uint64 {
 	return params.EcrecoverGas
 }
 
-func (c *ecrecover) Run(input []byte) ([]byte, error) {
+func (c *ecrecover) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	const ecRecoverInputLength = 128
 
 	input = common.RightPadBytes(input, ecRecoverInputLength)
@@ -88,17 +97,17 @@ func (c *ecrecover) Run(input []byte) ([]byte, error) {
 
 	// tighter sig s values input homestead only apply to tx sigs
 	if !allZero(input[32:63]) || !crypto.ValidateSignatureValues(v, r, s, false) {
-		return nil, nil
+		return nil, gas, nil
 	}
 	// v needs to be at the end for libsecp256k1
 	pubKey, err := crypto.Ecrecover(input[:32], append(input[64:128], v))
 	// make sure the public key is a valid one
 	if err != nil {
-		return nil, nil
+		return nil, gas, nil
 	}
 
 	// the first byte of pubkey is bitcoin heritage
-	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), nil
+	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), gas, nil
 }
 
 // SHA256 implemented as a native contract.
@@ -111,9 +120,9 @@ type sha256hash struct{}
 func (c *sha256hash) RequiredGas(input []byte) uint64 {
 	return uint64(len(input)+31)/32*params.Sha256PerWordGas + params.Sha256BaseGas
 }
-func (c *sha256hash) Run(input []byte) ([]byte, error) {
+func (c *sha256hash) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	h := sha256.Sum256(input)
-	return h[:], nil
+	return h[:], gas, nil
 }
 
 // RIPMED160 implemented as a native contract.
@@ -126,10 +135,10 @@ type ripemd160hash struct{}
 func (c *ripemd160hash) RequiredGas(input []byte) uint64 {
 	return uint64(len(input)+31)/32*params.Ripemd160PerWordGas + params.Ripemd160BaseGas
 }
-func (c *ripemd160hash) Run(input []byte) ([]byte, error) {
+func (c *ripemd160hash) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	ripemd := ripemd160.New()
 	ripemd.Write(input)
-	return common.LeftPadBytes(ripemd.Sum(nil), 32), nil
+	return common.LeftPadBytes(ripemd.Sum(nil), 32), gas, nil
 }
 
 // data copy implemented as a native contract.
@@ -142,8 +151,8 @@ type dataCopy struct{}
 func (c *dataCopy) RequiredGas(input []byte) uint64 {
 	return uint64(len(input)+31)/32*params.IdentityPerWordGas + params.IdentityBaseGas
 }
-func (c *dataCopy) Run(in []byte) ([]byte, error) {
-	return in, nil
+func (c *dataCopy) Run(in []byte, gas uint64) ([]byte, uint64, error) {
+	return in, gas, nil
 }
 
 // bigModExp implements a native big integer exponential modular operation.
@@ -223,7 +232,7 @@ func (c *bigModExp) RequiredGas(input []byte) uint64 {
 	return gas.Uint64()
 }
 
-func (c *bigModExp) Run(input []byte) ([]byte, error) {
+func (c *bigModExp) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	var (
 		baseLen = new(big.Int).SetBytes(getData(input, 0, 32)).Uint64()
 		expLen  = new(big.Int).SetBytes(getData(input, 32, 32)).Uint64()
@@ -236,7 +245,7 @@ func (c *bigModExp) Run(input []byte) ([]byte, error) {
 	}
 	// Handle a special case when both the base and mod length is zero
 	if baseLen == 0 && modLen == 0 {
-		return []byte{}, nil
+		return []byte{}, gas, nil
 	}
 	// Retrieve the operands and execute the exponentiation
 	var (
@@ -246,9 +255,9 @@ func (c *bigModExp) Run(input []byte) ([]byte, error) {
 	)
 	if mod.BitLen() == 0 {
 		// Modulo 0 is undefined, return zero
-		return common.LeftPadBytes([]byte{}, int(modLen)), nil
+		return common.LeftPadBytes([]byte{}, int(modLen)), gas, nil
 	}
-	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), nil
+	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), gas, nil
 }
 
 // newCurvePoint unmarshals a binary blob into a bn256 elliptic curve point,
@@ -279,18 +288,18 @@ func (c *bn256Add) RequiredGas(input []byte) uint64 {
 	return params.Bn256AddGas
 }
 
-func (c *bn256Add) Run(input []byte) ([]byte, error) {
+func (c *bn256Add) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	x, err := newCurvePoint(getData(input, 0, 64))
 	if err != nil {
-		return nil, err
+		return nil, gas, err
 	}
 	y, err := newCurvePoint(getData(input, 64, 64))
 	if err != nil {
-		return nil, err
+		return nil, gas, err
 	}
 	res := new(bn256.G1)
 	res.Add(x, y)
-	return res.Marshal(), nil
+	return res.Marshal(), gas, nil
 }
 
 // bn256ScalarMul implements a native elliptic curve scalar multiplication.
@@ -301,14 +310,14 @@ func (c *bn256ScalarMul) RequiredGas(input []byte) uint64 {
 	return params.Bn256ScalarMulGas
 }
 
-func (c *bn256ScalarMul) Run(input []byte) ([]byte, error) {
+func (c *bn256ScalarMul) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	p, err := newCurvePoint(getData(input, 0, 64))
 	if err != nil {
-		return nil, err
+		return nil, gas, err
 	}
 	res := new(bn256.G1)
 	res.ScalarMult(p, new(big.Int).SetBytes(getData(input, 64, 32)))
-	return res.Marshal(), nil
+	return res.Marshal(), gas, nil
 }
 
 var (
@@ -330,10 +339,10 @@ func (c *bn256Pairing) RequiredGas(input []byte) uint64 {
 	return params.Bn256PairingBaseGas + uint64(len(input)/192)*params.Bn256PairingPerPointGas
 }
 
-func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
+func (c *bn256Pairing) Run(input []byte, gas uint64) ([]byte, uint64, error) {
 	// Handle some corner cases cheaply
 	if len(input)%192 > 0 {
-		return nil, errBadPairingInput
+		return nil, gas, errBadPairingInput
 	}
 	// Convert the input into a set of coordinates
 	var (
@@ -343,18 +352,18 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	for i := 0; i < len(input); i += 192 {
 		c, err := newCurvePoint(input[i : i+64])
 		if err != nil {
-			return nil, err
+			return nil, gas, err
 		}
 		t, err := newTwistPoint(input[i+64 : i+192])
 		if err != nil {
-			return nil, err
+			return nil, gas, err
 		}
 		cs = append(cs, c)
 		ts = append(ts, t)
 	}
 	// Execute the pairing checks and return the results
 	if bn256.PairingCheck(cs, ts) {
-		return true32Byte, nil
+		return true32Byte, gas, nil
 	}
-	return false32Byte, nil
+	return false32Byte, gas, nil
 }
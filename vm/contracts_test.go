@@ -32,6 +32,70 @@ type precompiledTest struct {
 	noBenchmark     bool // Benchmark primarily the worst-cases
 }
 
+// precompiledFailureTest defines the input/error pairs for precompiled
+// contract failure tests.
+type precompiledFailureTest struct {
+	input         string
+	expectedError string
+	name          string
+}
+
+// ecrecoverTests are the test data for the ecrecover precompiled contract.
+var ecrecoverTests = []precompiledTest{
+	{
+		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		expected: "000000000000000000000000ceaccac640adf55b2028469bd36ba501f28b699d",
+		name:     "valid",
+	}, {
+		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001d38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		expected: "",
+		name:     "invalid-v",
+	}, {
+		input:    "",
+		expected: "",
+		name:     "empty",
+	},
+}
+
+// sha256Tests are the test data for the sha256 precompiled contract.
+var sha256Tests = []precompiledTest{
+	{
+		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		expected: "811c7003375852fabd0d362e40e68607a12bdabae61a7d068fe5fdd1dbbf2a5d",
+		name:     "128",
+	}, {
+		input:    "",
+		expected: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		name:     "empty",
+	},
+}
+
+// ripemd160Tests are the test data for the ripemd160 precompiled contract.
+var ripemd160Tests = []precompiledTest{
+	{
+		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		expected: "0000000000000000000000009215b8d9882ff46f0dfde6684d78e831467f65e6",
+		name:     "128",
+	}, {
+		input:    "",
+		expected: "0000000000000000000000009c1185a5c5e9fc54612808977ee8f548b2258d31",
+		name:     "empty",
+	},
+}
+
+// identityTests are the test data for the identity precompiled contract.
+var identityTests = []precompiledTest{
+	{
+		input:    "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		expected: "38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e000000000000000000000000000000000000000000000000000000000000001b38d18acb67d25c8bb9942764b62f18e17054f66a817bd4295423adf9ed98873e789d1dd423d25f0772d2748d60f7e4b81bb14d086eba8e8e8efb6dcff8a4ae02",
+		name:     "128",
+	}, {
+		input:    "",
+		expected: "",
+		name:     "empty",
+	},
+}
+
 // modexpTests are the test and benchmark data for the modexp precompiled contract.
 var modexpTests = []precompiledTest{
 	{
@@ -114,6 +178,44 @@ var modexpTests = []precompiledTest{
 	},
 }
 
+// modexpSmallTests are the test data for the modexp precompiled contract with
+// small exponents and degenerate moduli, including the expected gas.
+var modexpSmallTests = []precompiledTest{
+	{
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"030205",
+		expected: "04",
+		gas:      0,
+		name:     "small-exp",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"000000000000000000000000000000000000000000000000000000000000000203ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		expected: "0000000000000000000000000000000000000000000000000000000000000008",
+		gas:      51,
+		name:     "small-exp-32",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"030200",
+		expected: "00",
+		gas:      0,
+		name:     "zero-mod",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"02",
+		expected: "",
+		gas:      0,
+		name:     "zero-mod-len",
+	},
+}
+
 // bn256AddTests are the test and benchmark data for the bn256 addition precompiled
 // contract.
 var bn256AddTests = []precompiledTest{
@@ -274,6 +376,36 @@ var bn256ScalarMulTests = []precompiledTest{
 	},
 }
 
+// bn256FailureTests are the inputs rejected by the bn256 precompiled contracts,
+// keyed by the precompile address.
+var bn256FailureTests = map[string][]precompiledFailureTest{
+	"06": {
+		{
+			input:         "0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+			expectedError: "bn256: malformed point",
+			name:          "not_on_curve",
+		},
+	},
+	"07": {
+		{
+			input:         "30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4700000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000002",
+			expectedError: "bn256: coordinate equals modulus",
+			name:          "coordinate_equals_modulus",
+		},
+	},
+	"08": {
+		{
+			input:         "0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+			expectedError: "bad elliptic curve pairing size",
+			name:          "bad_size",
+		}, {
+			input:         "000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001",
+			expectedError: "bn256: malformed point",
+			name:          "twist_not_on_curve",
+		},
+	},
+}
+
 // bn256PairingTests are the test and benchmark data for the bn256 pairing check
 // precompiled contract.
 var bn256PairingTests = []precompiledTest{
@@ -350,6 +482,19 @@ func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
 	})
 }
 
+func testPrecompiledFailure(addr string, test precompiledFailureTest, t *testing.T) {
+	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	in := common.Hex2Bytes(test.input)
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), p.RequiredGas(in))
+	t.Run(test.name, func(t *testing.T) {
+		_, err := RunPrecompiledContract(p, in, contract)
+		if err == nil || err.Error() != test.expectedError {
+			t.Errorf("Expected error [%v], got [%v]", test.expectedError, err)
+		}
+	})
+}
+
 func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
@@ -386,6 +531,54 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	})
 }
 
+// Tests the sample inputs from the ECRECOVER precompile.
+func TestPrecompiledEcrecover(t *testing.T) {
+	for _, test := range ecrecoverTests {
+		testPrecompiled("01", test, t)
+	}
+}
+
+// Tests the sample inputs from the SHA256 precompile.
+func TestPrecompiledSha256(t *testing.T) {
+	for _, test := range sha256Tests {
+		testPrecompiled("02", test, t)
+	}
+}
+
+// Tests the sample inputs from the RIPEMD precompile.
+func TestPrecompiledRipeMD(t *testing.T) {
+	for _, test := range ripemd160Tests {
+		testPrecompiled("03", test, t)
+	}
+}
+
+// Tests the sample inputs from the identity precompile.
+func TestPrecompiledIdentity(t *testing.T) {
+	for _, test := range identityTests {
+		testPrecompiled("04", test, t)
+	}
+}
+
+// Tests the gas cost of the hash and copy precompiles for a 128 bytes input.
+func TestPrecompiledHashGas(t *testing.T) {
+	in := make([]byte, 128)
+	tests := []struct {
+		addr string
+		gas  uint64
+	}{
+		{"01", 3000},
+		{"02", 60 + 4*12},
+		{"03", 600 + 4*120},
+		{"04", 15 + 4*3},
+	}
+	for _, test := range tests {
+		p := PrecompiledContractsByzantium[common.HexToAddress(test.addr)]
+		if gas := p.RequiredGas(in); gas != test.gas {
+			t.Errorf("%s: expected gas %d, got %d", test.addr, test.gas, gas)
+		}
+	}
+}
+
 // Benchmarks the sample inputs from the ECRECOVER precompile.
 func BenchmarkPrecompiledEcrecover(bench *testing.B) {
 	t := precompiledTest{
@@ -433,6 +626,23 @@ func TestPrecompiledModExp(t *testing.T) {
 	}
 }
 
+// Tests modexp with small exponents and zero moduli.
+func TestPrecompiledModExpSmall(t *testing.T) {
+	for _, test := range modexpSmallTests {
+		testPrecompiled("05", test, t)
+	}
+}
+
+// Tests the EIP 198 gas formula of the modexp precompile.
+func TestPrecompiledModExpGas(t *testing.T) {
+	p := PrecompiledContractsByzantium[common.HexToAddress("05")]
+	for _, test := range modexpSmallTests {
+		if gas := p.RequiredGas(common.Hex2Bytes(test.input)); gas != test.gas {
+			t.Errorf("%s: expected gas %d, got %d", test.name, test.gas, gas)
+		}
+	}
+}
+
 // Benchmarks the sample inputs from the ModExp EIP 198.
 func BenchmarkPrecompiledModExp(bench *testing.B) {
 	for _, test := range modexpTests {
@@ -475,9 +685,92 @@ func TestPrecompiledBn256Pairing(t *testing.T) {
 	}
 }
 
+// Tests that invalid curve points and malformed inputs are rejected by the
+// bn256 precompiles.
+func TestPrecompiledBn256Failure(t *testing.T) {
+	for addr, tests := range bn256FailureTests {
+		for _, test := range tests {
+			testPrecompiledFailure(addr, test, t)
+		}
+	}
+}
+
+// Tests the gas cost of the bn256 precompiles.
+func TestPrecompiledBn256Gas(t *testing.T) {
+	tests := []struct {
+		addr  string
+		input []byte
+		gas   uint64
+	}{
+		{"06", make([]byte, 128), 500},
+		{"07", make([]byte, 96), 40000},
+		{"08", nil, 100000},
+		{"08", make([]byte, 2*192), 100000 + 2*80000},
+	}
+	for _, test := range tests {
+		p := PrecompiledContractsByzantium[common.HexToAddress(test.addr)]
+		if gas := p.RequiredGas(test.input); gas != test.gas {
+			t.Errorf("%s: expected gas %d, got %d", test.addr, test.gas, gas)
+		}
+	}
+}
+
 // Behcnmarks the sample inputs from the elliptic curve pairing check EIP 197.
 func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 	for _, test := range bn256PairingTests {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// gasReporter is a precompiled contract reporting the gas it receives, and
+// consuming half of it.
+type gasReporter struct{}
+
+func (c *gasReporter) RequiredGas(input []byte) uint64 { return 10 }
+func (c *gasReporter) Run(input []byte, gas uint64) ([]byte, uint64, error) {
+	return new(big.Int).SetUint64(gas).Bytes(), gas / 2, nil
+}
+
+// gasMinter is a precompiled contract returning more gas than it receives.
+type gasMinter struct{}
+
+func (c *gasMinter) RequiredGas(input []byte) uint64 { return 10 }
+func (c *gasMinter) Run(input []byte, gas uint64) ([]byte, uint64, error) {
+	return nil, gas + 1, nil
+}
+
+// Tests that precompiled contracts receive the gas left and return the unused gas.
+func TestPrecompiledGasForwarding(t *testing.T) {
+	contract := NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), 100)
+	res, err := RunPrecompiledContract(&gasReporter{}, nil, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received := new(big.Int).SetBytes(res).Uint64(); received != 90 {
+		t.Errorf("Expected received gas 90, got %d", received)
+	}
+	if contract.Gas != 45 {
+		t.Errorf("Expected left over gas 45, got %d", contract.Gas)
+	}
+
+	// builtin precompiles leave the extra gas untouched
+	contract = NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), 100)
+	if _, err := RunPrecompiledContract(PrecompiledContractsByzantium[common.HexToAddress("04")], nil, contract); err != nil {
+		t.Fatal(err)
+	}
+	if contract.Gas != 100-15 {
+		t.Errorf("Expected left over gas %d, got %d", 100-15, contract.Gas)
+	}
+
+	// returning more gas than received fails
+	contract = NewContract(AccountRef(common.HexToAddress("1337")),
+		nil, new(big.Int), 100)
+	if _, err := RunPrecompiledContract(&gasMinter{}, nil, contract); err != ErrOutOfGas {
+		t.Errorf("Expected error %v, got %v", ErrOutOfGas, err)
+	}
+	if contract.Gas != 0 {
+		t.Errorf("Expected left over gas 0, got %d", contract.Gas)
+	}
+}
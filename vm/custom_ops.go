@@ -0,0 +1,55 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// CustomOpFunc implements an experimental operation, see Config.CustomOps.
+type CustomOpFunc func(ctx *OpContext) error
+
+// OpContext gives a custom operation access to the execution state.
+// Gas is charged through Contract.UseGas.
+type OpContext struct {
+	Contract *Contract
+	Memory   *Memory
+	stack    *Stack
+}
+
+// StackLen returns the number of items on the stack.
+func (ctx *OpContext) StackLen() int {
+	return ctx.stack.len()
+}
+
+// Peek returns a copy of the n'th item from the top of the stack.
+func (ctx *OpContext) Peek(n int) (*big.Int, error) {
+	if err := ctx.stack.require(n + 1); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(ctx.stack.Back(n)), nil
+}
+
+// Pop removes and returns the top item of the stack.
+func (ctx *OpContext) Pop() (*big.Int, error) {
+	if err := ctx.stack.require(1); err != nil {
+		return nil, err
+	}
+	return ctx.stack.pop(), nil
+}
+
+// Push pushes a copy of v onto the stack, wrapped to 256 bits.
+func (ctx *OpContext) Push(v *big.Int) error {
+	if ctx.stack.len() >= int(params.StackLimit) {
+		return fmt.Errorf("stack limit reached %d (%d)", ctx.stack.len(), params.StackLimit)
+	}
+	ctx.stack.push(math.U256(new(big.Int).Set(v)))
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+func TestCustomOps(t *testing.T) {
+	const opDouble OpCode = 0x0c
+	double := func(ctx *OpContext) error {
+		if !ctx.Contract.UseGas(GasFastestStep) {
+			return ErrOutOfGas
+		}
+		x, err := ctx.Pop()
+		if err != nil {
+			return err
+		}
+		return ctx.Push(x.Lsh(x, 1))
+	}
+
+	run := func(code string, ops map[OpCode]CustomOpFunc) ([]byte, uint64, error) {
+		evm := NewEVM(Context{BlockNumber: new(big.Int)}, NoopStateDB{}, params.TestChainConfig, Config{CustomOps: ops})
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), AccountRef(common.HexToAddress("1338")), new(big.Int), 100000)
+		bytecode := common.Hex2Bytes(code)
+		contract.SetCode(crypto.Keccak256Hash(bytecode), bytecode)
+		ret, err := evm.interpreter.Run(contract, nil)
+		return ret, 100000 - contract.Gas, err
+	}
+	ops := map[OpCode]CustomOpFunc{opDouble: double}
+
+	// mstore(0, double(21)) return(0, 32)
+	ret, gasUsed, err := run("60150c60005260206000f3", ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := new(big.Int).SetBytes(ret); v.Int64() != 42 {
+		t.Errorf("expected 42, got %v", v)
+	}
+	// 4 push1s, mstore, memory expansion and double
+	if expected := 5*GasFastestStep + 3 + GasFastestStep; gasUsed != expected {
+		t.Errorf("expected gas used %d, got %d", expected, gasUsed)
+	}
+
+	// empty stack
+	if _, _, err := run("0c", ops); err == nil {
+		t.Error("expected stack underflow error")
+	}
+	// not registered
+	if _, _, err := run("60150c", nil); err == nil || errors.Cause(err) != ErrInvalidOpcode {
+		t.Errorf("expected invalid opcode error, got %v", err)
+	}
+	// assigned opcodes are not overridden
+	if _, _, err := run("6015600101", map[OpCode]CustomOpFunc{ADD: double}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -16,7 +16,10 @@
 
 package vm
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrOutOfGas                 = errors.New("out of gas")
@@ -25,4 +28,34 @@ var (
 	ErrTraceLimitReached        = errors.New("the number of logs reached the specified limit")
 	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
 	ErrContractAddressCollision = errors.New("contract address collision")
+	ErrStorageWriteLimit        = errors.New("storage write limit reached")
+	ErrLivelock                 = errors.New("livelock detected")
+	ErrReturnTooLarge           = errors.New("return data too large")
+	ErrSponsorRejected          = errors.New("sponsor rejected")
+	ErrGasPriceTooLow           = errors.New("gas price too low")
+	ErrNonMonotonicTime         = errors.New("block time not later than parent")
+	ErrInvalidJump              = errors.New("invalid jump destination")
+	ErrAborted                  = errors.New("execution aborted")
+	ErrLogLimit                 = errors.New("log limit reached")
+	ErrContractPaused           = errors.New("contract paused")
+	ErrInvalidOpcode            = errors.New("invalid opcode")
+	ErrTransferTooLarge         = errors.New("transfer value too large")
+	ErrWriteProtection          = errors.New("evm: write protection")
 )
+
+// ExecError is returned by the interpreter when execution fails, other than reverted.
+// It carries the program counter and the opcode where the failure occurred.
+type ExecError struct {
+	PC  uint64
+	Op  OpCode
+	Err error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("%v (pc %d, opcode %v)", e.Err, e.PC, e.Op)
+}
+
+// Cause returns the underlying error, see github.com/pkg/errors.
+func (e *ExecError) Cause() error {
+	return e.Err
+}
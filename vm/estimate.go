@@ -0,0 +1,55 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CallRequest describes a call to be estimated. Contract creation if To is nil.
+type CallRequest struct {
+	From  common.Address
+	To    *common.Address
+	Data  []byte
+	Value *big.Int
+}
+
+// GasEstimate is the estimated gas of a CallRequest, and the error if failed.
+type GasEstimate struct {
+	Gas uint64
+	Err error
+}
+
+// EstimateGasBatch executes each request with gas up to cap, and returns gas used by each.
+// State changes of every request are reverted, and the contract creation counter
+// restored, so all requests are estimated against the same base state.
+func (evm *EVM) EstimateGasBatch(reqs []CallRequest, cap uint64) []GasEstimate {
+	estimates := make([]GasEstimate, 0, len(reqs))
+	for _, req := range reqs {
+		var (
+			snapshot    = evm.StateDB.Snapshot()
+			writes      = evm.storageWrites
+			logs        = evm.logCount
+			creations   = evm.contractCreationCount
+			leftOverGas uint64
+			err         error
+		)
+		if req.To == nil {
+			_, _, leftOverGas, err = evm.Create(AccountRef(req.From), req.Data, cap, req.Value)
+		} else {
+			_, leftOverGas, err = evm.Call(AccountRef(req.From), *req.To, req.Data, cap, req.Value)
+		}
+		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
+		evm.contractCreationCount = creations
+
+		estimates = append(estimates, GasEstimate{cap - leftOverGas, err})
+	}
+	return estimates
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/runtime/statedb"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+)
+
+func TestEstimateGasBatch(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	setter := common.Address(thor.BytesToAddress([]byte("setter")))
+	reverter := common.Address(thor.BytesToAddress([]byte("reverter")))
+	// sstore(0, 1)
+	st.SetCode(thor.Address(setter), common.Hex2Bytes("6001600055"))
+	// revert(0, 0)
+	st.SetCode(thor.Address(reverter), common.Hex2Bytes("60006000fd"))
+
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{})
+	from := common.HexToAddress("1337")
+
+	estimates := evm.EstimateGasBatch([]CallRequest{
+		{From: from, To: &setter},
+		{From: from, To: &reverter},
+		// estimated against the same base state, so the slot is set from zero again
+		{From: from, To: &setter},
+	}, 100000)
+
+	expected := []GasEstimate{
+		{3*2 + params.SstoreSetGas, nil},
+		{3 * 2, errExecutionReverted},
+		{3*2 + params.SstoreSetGas, nil},
+	}
+	if len(estimates) != len(expected) {
+		t.Fatalf("expected %d estimates, got %d", len(expected), len(estimates))
+	}
+	for i, e := range expected {
+		if estimates[i] != e {
+			t.Errorf("request %d: expected %+v, got %+v", i, e, estimates[i])
+		}
+	}
+	if got := st.GetStorage(thor.Address(setter), thor.Bytes32{}); !got.IsZero() {
+		t.Errorf("expected storage reverted, got %v", got)
+	}
+}
+
+func TestEstimateGasBatchCreate(t *testing.T) {
+	var counters []uint32
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			counters = append(counters, counter)
+			return common.BytesToAddress([]byte{byte(counter + 1)})
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{})
+
+	from := common.HexToAddress("1337")
+	estimates := evm.EstimateGasBatch([]CallRequest{{From: from}, {From: from}}, 100000)
+	if estimates[0] != estimates[1] {
+		t.Errorf("expected same estimates, got %+v and %+v", estimates[0], estimates[1])
+	}
+	if len(counters) != 2 || counters[0] != 0 || counters[1] != 0 {
+		t.Errorf("expected both created with counter 0, got %v", counters)
+	}
+}
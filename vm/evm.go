@@ -47,6 +47,9 @@ type (
 
 	// OnSuicideContractFunc callback when suicide contract.
 	OnSuicideContractFunc func(evm *EVM, contractAddr common.Address, tokenReceiver common.Address)
+
+	// IsPausedFunc returns whether the contract is paused, see Config.EnforcePause.
+	IsPausedFunc func(StateDB, common.Address) bool
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
@@ -63,6 +66,19 @@ func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	return evm.interpreter.Run(contract, input)
 }
 
+// isPrecompile returns whether addr is a precompiled contract under current rules.
+func (evm *EVM) isPrecompile(addr common.Address) bool {
+	if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
+		return PrecompiledContractsByzantium[addr] != nil
+	}
+	return PrecompiledContractsHomestead[addr] != nil
+}
+
+// isPaused returns whether calls to addr are rejected as paused.
+func (evm *EVM) isPaused(addr common.Address) bool {
+	return evm.vmConfig.EnforcePause && evm.IsPaused != nil && evm.IsPaused(evm.StateDB, addr)
+}
+
 // Context provides the EVM with auxiliary information. Once provided
 // it shouldn't be modified.
 type Context struct {
@@ -78,6 +94,7 @@ type Context struct {
 	InterceptContractCall InterceptContractCallFunc
 	OnCreateContract      OnCreateContractFunc
 	OnSuicideContract     OnSuicideContractFunc
+	IsPaused              IsPausedFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -89,6 +106,18 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	RandaoSeed  uint64         // Seeds a deterministic DIFFICULTY if Difficulty is zero (disabled if zero)
+	BaseFee     *big.Int       // Base fee per gas burned under EIP-1559, see FeeSplit (zero if nil)
+
+	StateRoot common.Hash // Root of the state executed on, keying Config.ViewCache
+}
+
+// ValidateMonotonic checks the block time is later than the parent block time.
+func (ctx *Context) ValidateMonotonic(parentTime *big.Int) error {
+	if ctx.Time == nil || ctx.Time.Cmp(parentTime) <= 0 {
+		return ErrNonMonotonicTime
+	}
+	return nil
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -99,7 +128,7 @@ type Context struct {
 // specific errors should ever be performed. The interpreter makes
 // sure that any errors generated are to be considered faulty code.
 //
-// The EVM should never be reused and is not thread safe.
+// The EVM is not thread safe and should be reset by Reset before reused.
 type EVM struct {
 	// Context provides auxiliary blockchain related information
 	Context
@@ -129,11 +158,34 @@ type EVM struct {
 	// contract created during execution.
 	// this value is important for generating contract address.
 	contractCreationCount uint32
+
+	// storage writes performed during execution, excluding the ones
+	// rolled back. it's checked against vmConfig.MaxStorageWrites.
+	storageWrites int
+
+	// logs emitted during execution, excluding the ones rolled back.
+	// it's checked against vmConfig.MaxLogs.
+	logCount int
+
+	// storage values before the first SSTORE to each slot during execution,
+	// for net gas metering if vmConfig.EIP2200 set.
+	originalStates OriginalStates
+
+	// the deepest stack size reached during execution,
+	// tracked if vmConfig.TrackStack is set.
+	maxStackDepth int
+
+	// runtime codes loaded by PreloadCode, keyed by code hash.
+	// it's kept across Reset.
+	codeCache map[common.Hash][]byte
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
-// only ever be used *once*.
+// only ever be used *once* unless it's reset.
 func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmConfig Config) *EVM {
+	if ctx.GasPrice == nil {
+		ctx.GasPrice = suggestGasPrice(ctx, vmConfig.GasOracle)
+	}
 	evm := &EVM{
 		Context:     ctx,
 		StateDB:     statedb,
@@ -146,6 +198,58 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 	return evm
 }
 
+// suggestGasPrice returns gas price suggested by the oracle, or zero if no oracle.
+func suggestGasPrice(ctx Context, oracle GasOracle) *big.Int {
+	if oracle != nil {
+		if price := oracle.SuggestGasPrice(ctx); price != nil {
+			return price
+		}
+	}
+	return new(big.Int)
+}
+
+// Reset clears per-execution state and rebinds the context and state db,
+// so that the EVM can be reused for the next execution.
+// Refund, logs and snapshots are kept by the state db, which is replaced.
+func (evm *EVM) Reset(ctx Context, statedb StateDB) {
+	if ctx.GasPrice == nil {
+		ctx.GasPrice = suggestGasPrice(ctx, evm.vmConfig.GasOracle)
+	}
+	evm.Context = ctx
+	evm.StateDB = statedb
+	evm.depth = 0
+	evm.chainRules = evm.chainConfig.Rules(ctx.BlockNumber)
+	atomic.StoreInt32(&evm.abort, 0)
+	evm.callGasTemp = 0
+	evm.contractCreationCount = 0
+	evm.storageWrites = 0
+	evm.logCount = 0
+	evm.originalStates = nil
+	evm.maxStackDepth = 0
+
+	// the jump table depends on block number, and the int pool is reusable
+	intPool := evm.interpreter.intPool
+	evm.interpreter = NewInterpreter(evm, evm.vmConfig)
+	evm.interpreter.intPool = intPool
+}
+
+// FeeSplit splits the fee of gasUsed at the context gas price into the base fee
+// portion, which is burned under EIP-1559, and the remainder tipped to the proposer.
+// The base fee per gas is capped to the gas price.
+func (evm *EVM) FeeSplit(gasUsed uint64) (burned, tip *big.Int) {
+	baseFee := new(big.Int)
+	if evm.BaseFee != nil {
+		baseFee.Set(evm.BaseFee)
+	}
+	if baseFee.Cmp(evm.GasPrice) > 0 {
+		baseFee.Set(evm.GasPrice)
+	}
+	gas := new(big.Int).SetUint64(gasUsed)
+	burned = new(big.Int).Mul(baseFee, gas)
+	tip = new(big.Int).Mul(new(big.Int).Sub(evm.GasPrice, baseFee), gas)
+	return burned, tip
+}
+
 // Cancel cancels any running EVM operation. This may be called concurrently and
 // it's safe to be called multiple times.
 func (evm *EVM) Cancel() {
@@ -157,11 +261,66 @@ func (evm *EVM) Depth() int {
 	return evm.depth
 }
 
+// MaxStackDepth returns the deepest stack size reached during execution.
+// It's always zero unless Config.TrackStack is set.
+func (evm *EVM) MaxStackDepth() int {
+	return evm.maxStackDepth
+}
+
+// StorageWrites returns the number of storage writes counted against Config.MaxStorageWrites.
+func (evm *EVM) StorageWrites() int {
+	return evm.storageWrites
+}
+
+// SetStorageWrites sets the number of storage writes already counted, so that
+// Config.MaxStorageWrites is shared with previous executions of the same
+// transaction.
+func (evm *EVM) SetStorageWrites(n int) {
+	evm.storageWrites = n
+}
+
+// LogCount returns the number of logs counted against Config.MaxLogs.
+func (evm *EVM) LogCount() int {
+	return evm.logCount
+}
+
+// SetLogCount sets the number of logs already counted, so that Config.MaxLogs
+// is shared with previous executions of the same transaction.
+func (evm *EVM) SetLogCount(n int) {
+	evm.logCount = n
+}
+
+// SetOriginalStates sets the storage values recorded as original for net gas metering,
+// see Config.EIP2200. Values first seen by this EVM are added to states, so that
+// states shared with executions of the same transaction keep values at its beginning.
+func (evm *EVM) SetOriginalStates(states OriginalStates) {
+	evm.originalStates = states
+}
+
+// isHomestead returns whether homestead rules apply, which can be overridden by vmConfig.Homestead.
+func (evm *EVM) isHomestead() bool {
+	if homestead := evm.vmConfig.Homestead; homestead != nil {
+		return *homestead
+	}
+	return evm.ChainConfig().IsHomestead(evm.BlockNumber)
+}
+
+// gasPriceTooLow returns whether the gas price is below vmConfig.MinGasPrice.
+func (evm *EVM) gasPriceTooLow() bool {
+	min := evm.vmConfig.MinGasPrice
+	return min != nil && evm.GasPrice.Cmp(min) < 0
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
 // execution error or failed value transfer.
 func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, leftOverGas uint64, err error) {
+	// treat nil value as zero
+	if value == nil {
+		value = new(big.Int)
+	}
+
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
@@ -170,14 +329,39 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the callee is paused
+	if evm.isPaused(addr) {
+		return nil, gas, ErrContractPaused
+	}
+	// Fail if the gas price is below the configured minimum
+	if evm.gasPriceTooLow() {
+		return nil, gas, ErrGasPriceTooLow
+	}
+	// Fail if value is transferred in read-only mode
+	if evm.vmConfig.ReadOnly && value.Sign() != 0 {
+		return nil, gas, ErrWriteProtection
+	}
+	// Fail if the value exceeds the configured maximum
+	if max := evm.vmConfig.MaxTransferValue; max != nil && value.Cmp(max) > 0 {
+		return nil, gas, ErrTransferTooLarge
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
 	}
+	// Route calls to the zero address to the handler
+	if handler := evm.vmConfig.ZeroAddressHandler; handler != nil && addr == (common.Address{}) {
+		if ret, err = handler(input); err != nil {
+			return nil, 0, err
+		}
+		return ret, gas, nil
+	}
 
 	var (
 		to       = AccountRef(addr)
 		snapshot = evm.StateDB.Snapshot()
+		writes   = evm.storageWrites
+		logs     = evm.logCount
 	)
 	if !evm.StateDB.Exist(addr) {
 		precompiles := PrecompiledContractsHomestead
@@ -196,10 +380,19 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 	evm.Transfer(evm.StateDB, caller.Address(), to.Address(), value)
 
+	// Short-circuit the call to empty or STOP-only code, which always succeeds
+	// without consuming gas.
+	codeHash := evm.StateDB.GetCodeHash(addr)
+	code := evm.code(addr, codeHash)
+	if (len(code) == 0 || (len(code) == 1 && OpCode(code[0]) == STOP)) &&
+		!evm.vmConfig.Debug && evm.vmConfig.CoverageMap == nil && !evm.isPrecompile(addr) {
+		return nil, gas, nil
+	}
+
 	// Initialise a new contract and set the code that is to be used by the EVM.
 	// The contract is a scoped environment for this execution context only.
 	contract := NewContract(caller, to, value, gas)
-	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
+	contract.SetCallCode(&addr, codeHash, code)
 
 	// Capture the tracer start/end events in debug mode
 	if evm.vmConfig.Debug && evm.depth == 0 {
@@ -217,6 +410,8 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	// when we're in homestead this also counts for code storage gas errors.
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -232,6 +427,11 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 // CallCode differs from Call in the sense that it executes the given address'
 // code with the caller as context.
 func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, leftOverGas uint64, err error) {
+	// treat nil value as zero
+	if value == nil {
+		value = new(big.Int)
+	}
+
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
@@ -240,6 +440,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the callee is paused
+	if evm.isPaused(addr) {
+		return nil, gas, ErrContractPaused
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
@@ -247,17 +451,22 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	var (
 		snapshot = evm.StateDB.Snapshot()
+		writes   = evm.storageWrites
+		logs     = evm.logCount
 		to       = AccountRef(caller.Address())
 	)
 	// initialise a new contract and set the code that is to be used by the
 	// EVM. The contract is a scoped environment for this execution context
 	// only.
 	contract := NewContract(caller, to, value, gas)
-	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
+	codeHash := evm.StateDB.GetCodeHash(addr)
+	contract.SetCallCode(&addr, codeHash, evm.code(addr, codeHash))
 
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -278,19 +487,28 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the callee is paused
+	if evm.isPaused(addr) {
+		return nil, gas, ErrContractPaused
+	}
 
 	var (
 		snapshot = evm.StateDB.Snapshot()
+		writes   = evm.storageWrites
+		logs     = evm.logCount
 		to       = AccountRef(caller.Address())
 	)
 
 	// Initialise a new contract and make initialise the delegate values
 	contract := NewContract(caller, to, nil, gas).AsDelegate()
-	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
+	codeHash := evm.StateDB.GetCodeHash(addr)
+	contract.SetCallCode(&addr, codeHash, evm.code(addr, codeHash))
 
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -310,6 +528,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the callee is paused
+	if evm.isPaused(addr) {
+		return nil, gas, ErrContractPaused
+	}
 	// Make sure the readonly is only set if we aren't in readonly yet
 	// this makes also sure that the readonly flag isn't removed for
 	// child calls.
@@ -318,15 +540,32 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		defer func() { evm.interpreter.readOnly = false }()
 	}
 
+	// only top level calls of a read-only EVM can't observe changes not reflected by the state root.
+	// calls being traced are executed anyway.
+	cache := evm.vmConfig.ViewCache
+	if !evm.vmConfig.ReadOnly || evm.vmConfig.Debug || evm.depth > 0 {
+		cache = nil
+	}
+	var cacheKey common.Hash
+	if cache != nil {
+		cacheKey = viewCacheKey(caller.Address(), addr, evm.StateRoot, evm.BlockNumber, input)
+		if ret, gasUsed, ok := cache.Get(cacheKey); ok && gasUsed <= gas {
+			return ret, gas - gasUsed, nil
+		}
+	}
+
 	var (
 		to       = AccountRef(addr)
 		snapshot = evm.StateDB.Snapshot()
+		writes   = evm.storageWrites
+		logs     = evm.logCount
 	)
 	// Initialise a new contract and set the code that is to be used by the
 	// EVM. The contract is a scoped environment for this execution context
 	// only.
 	contract := NewContract(caller, to, new(big.Int), gas)
-	contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), evm.StateDB.GetCode(addr))
+	codeHash := evm.StateDB.GetCodeHash(addr)
+	contract.SetCallCode(&addr, codeHash, evm.code(addr, codeHash))
 
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
@@ -334,33 +573,64 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	ret, err = run(evm, contract, input)
 	if err != nil {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
+	} else if cache != nil {
+		cache.Set(cacheKey, ret, gas-contract.Gas)
 	}
 	return ret, contract.Gas, err
 }
 
+// viewCacheKey computes the key of ViewCache.
+func viewCacheKey(caller, addr common.Address, stateRoot common.Hash, blockNumber *big.Int, input []byte) common.Hash {
+	return crypto.Keccak256Hash(caller.Bytes(), addr.Bytes(), stateRoot.Bytes(), common.BigToHash(blockNumber).Bytes(), input)
+}
+
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	// treat nil value as zero
+	if value == nil {
+		value = new(big.Int)
+	}
 
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, common.Address{}, gas, ErrDepth
 	}
+	if evm.gasPriceTooLow() {
+		return nil, common.Address{}, gas, ErrGasPriceTooLow
+	}
+	if evm.vmConfig.ReadOnly {
+		return nil, common.Address{}, gas, ErrWriteProtection
+	}
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
 	// Ensure there's no existing contract already at the designated address
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
-
-	//contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	if evm.vmConfig.ResetNonceOnFailedCreate {
+		defer func() {
+			if err != nil {
+				evm.StateDB.SetNonce(caller.Address(), nonce)
+			}
+		}()
+	}
 
 	// differ with ethereum here!!!
 	// let runtime make new contract address
-	contractAddr = evm.NewContractAddress(evm, evm.contractCreationCount)
+	switch {
+	case evm.vmConfig.AddressDeriver != nil:
+		contractAddr = evm.vmConfig.AddressDeriver(caller.Address(), nonce, code)
+	case evm.NewContractAddress != nil:
+		contractAddr = evm.NewContractAddress(evm, evm.contractCreationCount)
+	default:
+		contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	}
 	evm.contractCreationCount++
 
 	//
@@ -370,6 +640,8 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	}
 	// Create a new account on the state
 	snapshot := evm.StateDB.Snapshot()
+	writes := evm.storageWrites
+	logs := evm.logCount
 	evm.StateDB.CreateAccount(contractAddr)
 	if evm.ChainConfig().IsEIP158(evm.BlockNumber) {
 		evm.StateDB.SetNonce(contractAddr, 1)
@@ -417,8 +689,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in homestead this also counts for code storage gas errors.
-	if maxCodeSizeExceeded || (err != nil && (evm.ChainConfig().IsHomestead(evm.BlockNumber) || err != ErrCodeStoreOutOfGas)) {
+	if maxCodeSizeExceeded || (err != nil && (evm.isHomestead() || err != ErrCodeStoreOutOfGas)) {
 		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.storageWrites = writes
+		evm.logCount = logs
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -433,6 +707,123 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// IsContract returns whether the account at addr has non-empty code,
+// which is the same as EXTCODESIZE > 0.
+func (evm *EVM) IsContract(addr common.Address) bool {
+	return evm.StateDB.GetCodeSize(addr) > 0
+}
+
+// PreloadCode loads runtime codes of the given accounts into a cache,
+// so that later calls to them skip loading code from the state db.
+// The cache is keyed by code hash, and is kept across Reset.
+func (evm *EVM) PreloadCode(addrs []common.Address) {
+	for _, addr := range addrs {
+		hash := evm.StateDB.GetCodeHash(addr)
+		if hash == (common.Hash{}) || hash == emptyCodeHash {
+			continue
+		}
+		if _, ok := evm.codeCache[hash]; ok {
+			continue
+		}
+		if evm.codeCache == nil {
+			evm.codeCache = make(map[common.Hash][]byte)
+		}
+		evm.codeCache[hash] = evm.StateDB.GetCode(addr)
+	}
+}
+
+// code returns the runtime code of the account with the given code hash,
+// from the preloaded cache if present.
+func (evm *EVM) code(addr common.Address, hash common.Hash) []byte {
+	if code, ok := evm.codeCache[hash]; ok {
+		return code
+	}
+	return evm.StateDB.GetCode(addr)
+}
+
+// storageSlot identifies a storage slot of an account.
+type storageSlot struct {
+	addr common.Address
+	key  common.Hash
+}
+
+// OriginalStates holds storage values before the first SSTORE to each slot.
+type OriginalStates map[storageSlot]common.Hash
+
+// originalState returns the value of the storage slot at the beginning of execution.
+// The current value is remembered as the original at the first call for each slot,
+// which must be made before the slot is ever written.
+func (evm *EVM) originalState(addr common.Address, key common.Hash, current common.Hash) common.Hash {
+	slot := storageSlot{addr, key}
+	if original, ok := evm.originalStates[slot]; ok {
+		return original
+	}
+	if evm.originalStates == nil {
+		evm.originalStates = make(OriginalStates)
+	}
+	evm.originalStates[slot] = current
+	return current
+}
+
+// LastReturnData returns the return data of the most recent sub-call
+// made in the current execution frame.
+func (evm *EVM) LastReturnData() []byte {
+	return evm.interpreter.returnData
+}
+
+// CurrentCodeHash returns the code hash of the currently executing contract.
+// Zero hash returned if not executing.
+func (evm *EVM) CurrentCodeHash() common.Hash {
+	if evm.interpreter.contract == nil {
+		return common.Hash{}
+	}
+	return evm.interpreter.contract.CodeHash
+}
+
+// verifyPureGas is the gas limit of the static call made by VerifyPure.
+const verifyPureGas uint64 = 10000000
+
+// VerifyPure static calls the contract at addr with input, and returns whether
+// it completes without emitting logs or writing storage.
+// Errors other than attempting state modification are returned.
+func (evm *EVM) VerifyPure(addr common.Address, input []byte) (bool, error) {
+	_, _, err := evm.StaticCall(AccountRef(common.Address{}), addr, input, verifyPureGas)
+	if execErr, ok := err.(*ExecError); ok && execErr.Err == ErrWriteProtection {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExtractSelectors returns function selectors likely implemented by the contract at addr,
+// by heuristically scanning the dispatch table of its code. It's for tooling only.
+func (evm *EVM) ExtractSelectors(addr common.Address) [][4]byte {
+	return dispatchSelectors(evm.StateDB.GetCode(addr))
+}
+
+// supportsInterfaceID is the selector of ERC-165's supportsInterface(bytes4).
+var supportsInterfaceID = []byte{0x01, 0xff, 0xc9, 0xa7}
+
+// supportsInterfaceGas is the gas limit of supportsInterface call defined by ERC-165.
+const supportsInterfaceGas uint64 = 30000
+
+// SupportsInterface queries whether the contract at addr implements the interface,
+// by static calling ERC-165's supportsInterface.
+// It returns false if the contract returns malformed data.
+func (evm *EVM) SupportsInterface(addr common.Address, interfaceID [4]byte) (bool, error) {
+	input := append(append([]byte(nil), supportsInterfaceID...), common.RightPadBytes(interfaceID[:], 32)...)
+	ret, _, err := evm.StaticCall(AccountRef(common.Address{}), addr, input, supportsInterfaceGas)
+	if err != nil {
+		return false, err
+	}
+	if len(ret) != 32 {
+		return false, nil
+	}
+	return common.BytesToHash(ret) == common.BigToHash(big1), nil
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
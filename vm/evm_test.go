@@ -0,0 +1,1365 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/runtime/statedb"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+)
+
+type existStateDB struct {
+	NoopStateDB
+}
+
+func (existStateDB) Exist(common.Address) bool { return true }
+
+func TestNilValue(t *testing.T) {
+	var transfers []*big.Int
+	ctx := Context{
+		CanTransfer: func(_ StateDB, _ common.Address, amount *big.Int) bool {
+			return amount.Sign() == 0
+		},
+		Transfer: func(_ StateDB, _, _ common.Address, amount *big.Int) {
+			transfers = append(transfers, amount)
+		},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			return common.BytesToAddress([]byte{byte(counter)})
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, existStateDB{}, params.TestChainConfig, Config{})
+	if evm.GasPrice == nil || evm.GasPrice.Sign() != 0 {
+		t.Errorf("expected zero gas price, got %v", evm.GasPrice)
+	}
+
+	caller := AccountRef(common.HexToAddress("1337"))
+	if _, _, err := evm.Call(caller, common.HexToAddress("1338"), nil, 100000, nil); err != nil {
+		t.Errorf("Call: unexpected error %v", err)
+	}
+	if _, _, err := evm.CallCode(caller, common.HexToAddress("1338"), nil, 100000, nil); err != nil {
+		t.Errorf("CallCode: unexpected error %v", err)
+	}
+	if _, _, _, err := evm.Create(caller, nil, 100000, nil); err != nil {
+		t.Errorf("Create: unexpected error %v", err)
+	}
+
+	// CallCode doesn't transfer
+	if len(transfers) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(transfers))
+	}
+	for _, amount := range transfers {
+		if amount == nil || amount.Sign() != 0 {
+			t.Errorf("expected zero transfer, got %v", amount)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			return common.BytesToAddress([]byte{0xc0, byte(counter)})
+		},
+		BlockNumber: new(big.Int),
+	}
+
+	// sstore(0, 1) mstore(0, create(0, 0, 0)) return(0, 32)
+	code := common.Hex2Bytes("6001600055600060006000f060005260206000f3")
+	addr := thor.BytesToAddress([]byte("contract"))
+	caller := AccountRef(common.HexToAddress("1337"))
+
+	newState := func() *state.State {
+		st, _ := state.New(thor.Bytes32{}, kv)
+		st.SetCode(addr, code)
+		return st
+	}
+
+	st1 := newState()
+	evm := NewEVM(ctx, statedb.New(st1), params.TestChainConfig, Config{MaxStorageWrites: 1})
+	ret1, _, err := evm.Call(caller, common.Address(addr), nil, 1000000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st2 := newState()
+	evm.Reset(ctx, statedb.New(st2))
+	if evm.Depth() != 0 {
+		t.Errorf("expected depth 0, got %d", evm.Depth())
+	}
+	ret2, _, err := evm.Call(caller, common.Address(addr), nil, 1000000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// contract creation counter is reset
+	if common.BytesToAddress(ret1) != common.BytesToAddress(ret2) {
+		t.Errorf("expected same created address, got %x and %x", ret1, ret2)
+	}
+	// each execution writes into its own state
+	one := thor.BytesToBytes32([]byte{1})
+	if v := st1.GetStorage(addr, thor.Bytes32{}); v != one {
+		t.Errorf("expected %v, got %v", one, v)
+	}
+	if v := st2.GetStorage(addr, thor.Bytes32{}); v != one {
+		t.Errorf("expected %v, got %v", one, v)
+	}
+}
+
+func TestAddressDeriver(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	caller := AccountRef(common.HexToAddress("1337"))
+	initCode := []byte{byte(STOP)}
+
+	// standard RLP-nonce scheme by default
+	evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{})
+	_, addr, _, err := evm.Create(caller, initCode, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := crypto.CreateAddress(caller.Address(), 0); addr != expected {
+		t.Errorf("expected %x, got %x", expected, addr)
+	}
+
+	// custom deterministic scheme
+	deriver := func(caller common.Address, nonce uint64, initCode []byte) common.Address {
+		return common.BytesToAddress(crypto.Keccak256(caller.Bytes(), initCode))
+	}
+	ctx.NewContractAddress = func(*EVM, uint32) common.Address {
+		return common.HexToAddress("bad")
+	}
+	evm = NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{AddressDeriver: deriver})
+	_, addr, _, err = evm.Create(caller, initCode, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := common.BytesToAddress(crypto.Keccak256(caller.Address().Bytes(), initCode)); addr != expected {
+		t.Errorf("expected %x, got %x", expected, addr)
+	}
+}
+
+func TestSupportsInterface(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	// returns interfaceID == 0x01ffc9a7 || interfaceID == 0x12345678
+	addr := thor.BytesToAddress([]byte("erc165"))
+	st.SetCode(addr, common.Hex2Bytes("6004357c01000000000000000000000000000000000000000000000000000000009004806301ffc9a714906312345678141760005260206000f3"))
+
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{})
+
+	tests := []struct {
+		addr        thor.Address
+		interfaceID [4]byte
+		expected    bool
+	}{
+		{addr, [4]byte{0x01, 0xff, 0xc9, 0xa7}, true},
+		{addr, [4]byte{0x12, 0x34, 0x56, 0x78}, true},
+		{addr, [4]byte{0xff, 0xff, 0xff, 0xff}, false},
+		{thor.BytesToAddress([]byte("nocode")), [4]byte{0x12, 0x34, 0x56, 0x78}, false},
+	}
+	for _, test := range tests {
+		ok, err := evm.SupportsInterface(common.Address(test.addr), test.interfaceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != test.expected {
+			t.Errorf("%x: expected %v, got %v", test.interfaceID, test.expected, ok)
+		}
+	}
+}
+
+func TestDetectLivelock(t *testing.T) {
+	ctx := Context{BlockNumber: new(big.Int)}
+	run := func(code []byte, detect bool) (uint64, error) {
+		evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{DetectLivelock: detect})
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), AccountRef(common.HexToAddress("1338")), new(big.Int), 1000000)
+		contract.SetCode(crypto.Keccak256Hash(code), code)
+		_, err := evm.interpreter.Run(contract, nil)
+		return contract.Gas, err
+	}
+
+	// for {}
+	loop := common.Hex2Bytes("5b600056")
+	gas, err := run(loop, true)
+	if errors.Cause(err) != ErrLivelock {
+		t.Fatalf("expected %v, got %v", ErrLivelock, err)
+	}
+	if gas == 0 {
+		t.Error("expected gas left")
+	}
+	if _, err := run(loop, false); errors.Cause(err) != ErrOutOfGas {
+		t.Errorf("expected %v, got %v", ErrOutOfGas, err)
+	}
+
+	// for i := 100; i > 0; i-- {}
+	countdown := common.Hex2Bytes("60645b6001900380600257")
+	if _, err := run(countdown, true); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMaxReturnBytes(t *testing.T) {
+	ctx := Context{BlockNumber: new(big.Int)}
+	tests := []struct {
+		code     string
+		expected error
+	}{
+		{"60206000f3", nil},                             // return 32 bytes
+		{"60216000f3", ErrReturnTooLarge},               // return 33 bytes
+		{"60406000fd", ErrReturnTooLarge},               // revert 64 bytes
+		{"67ffffffffffffffff6000f3", ErrReturnTooLarge}, // return 2^64-1 bytes
+	}
+	for _, test := range tests {
+		evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{MaxReturnBytes: 32})
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), AccountRef(common.HexToAddress("1338")), new(big.Int), 1000000)
+		code := common.Hex2Bytes(test.code)
+		contract.SetCode(crypto.Keccak256Hash(code), code)
+		if _, err := evm.interpreter.Run(contract, nil); errors.Cause(err) != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.code, test.expected, err)
+		}
+	}
+}
+
+func TestMinGasPrice(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			return common.BytesToAddress([]byte{byte(counter)})
+		},
+		BlockNumber: new(big.Int),
+	}
+	caller := AccountRef(common.HexToAddress("1337"))
+	for _, test := range []struct {
+		gasPrice int64
+		expected error
+	}{
+		{99, ErrGasPriceTooLow},
+		{100, nil},
+		{101, nil},
+	} {
+		ctx.GasPrice = big.NewInt(test.gasPrice)
+		evm := NewEVM(ctx, existStateDB{}, params.TestChainConfig, Config{MinGasPrice: big.NewInt(100)})
+
+		_, gas, err := evm.Call(caller, common.HexToAddress("1338"), nil, 100000, nil)
+		if err != test.expected {
+			t.Errorf("Call at %d: expected %v, got %v", test.gasPrice, test.expected, err)
+		}
+		if gas != 100000 {
+			t.Errorf("Call at %d: expected no gas used, got %d left", test.gasPrice, gas)
+		}
+		if _, _, _, err := evm.Create(caller, nil, 100000, nil); err != test.expected {
+			t.Errorf("Create at %d: expected %v, got %v", test.gasPrice, test.expected, err)
+		}
+	}
+
+	// no minimum
+	evm := NewEVM(Context{CanTransfer: ctx.CanTransfer, Transfer: ctx.Transfer, BlockNumber: new(big.Int)}, existStateDB{}, params.TestChainConfig, Config{})
+	if _, _, err := evm.Call(caller, common.HexToAddress("1338"), nil, 100000, nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRandaoSeed(t *testing.T) {
+	difficulty := func(seed uint64, number int64) common.Hash {
+		ctx := Context{BlockNumber: big.NewInt(number), Difficulty: new(big.Int), RandaoSeed: seed}
+		evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{})
+		// mstore(0, difficulty()) return(0, 32)
+		code := common.Hex2Bytes("4460005260206000f3")
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), AccountRef(common.HexToAddress("1338")), new(big.Int), 100000)
+		contract.SetCode(crypto.Keccak256Hash(code), code)
+		ret, err := evm.interpreter.Run(contract, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return common.BytesToHash(ret)
+	}
+
+	if difficulty(1, 10) != difficulty(1, 10) {
+		t.Error("expected same value for the same seed and block number")
+	}
+	if (difficulty(1, 10) == common.Hash{}) {
+		t.Error("expected non-zero value")
+	}
+	if difficulty(1, 10) == difficulty(2, 10) {
+		t.Error("expected different values for different seeds")
+	}
+	if difficulty(1, 10) == difficulty(1, 11) {
+		t.Error("expected different values for different block numbers")
+	}
+	if (difficulty(0, 10) != common.Hash{}) {
+		t.Error("expected zero difficulty without seed")
+	}
+}
+
+func TestIsContract(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			return common.BytesToAddress([]byte{0xc0, byte(counter)})
+		},
+		BlockNumber: new(big.Int),
+	}
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{})
+
+	// return(0, 1)
+	_, contractAddr, _, err := evm.Create(AccountRef(common.HexToAddress("1337")), common.Hex2Bytes("60016000f3"), 100000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eoa := thor.BytesToAddress([]byte("eoa"))
+	st.SetBalance(eoa, big.NewInt(1))
+
+	tests := []struct {
+		addr     common.Address
+		expected bool
+	}{
+		{contractAddr, true},
+		{common.Address(eoa), false},
+		{common.HexToAddress("dead"), false},
+	}
+	for _, test := range tests {
+		if isContract := evm.IsContract(test.addr); isContract != test.expected {
+			t.Errorf("%x: expected %v, got %v", test.addr, test.expected, isContract)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	ctx := Context{BlockNumber: new(big.Int), Time: big.NewInt(1530000000)}
+	evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{})
+	// mstore(0, timestamp()) return(0, 32)
+	code := common.Hex2Bytes("4260005260206000f3")
+	contract := NewContract(AccountRef(common.HexToAddress("1337")), AccountRef(common.HexToAddress("1338")), new(big.Int), 100000)
+	contract.SetCode(crypto.Keccak256Hash(code), code)
+	ret, err := evm.interpreter.Run(contract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := new(big.Int).SetBytes(ret); v.Cmp(ctx.Time) != 0 {
+		t.Errorf("expected %v, got %v", ctx.Time, v)
+	}
+
+	tests := []struct {
+		parentTime int64
+		expected   error
+	}{
+		{1529999990, nil},
+		{1530000000, ErrNonMonotonicTime},
+		{1530000010, ErrNonMonotonicTime},
+	}
+	for _, test := range tests {
+		if err := ctx.ValidateMonotonic(big.NewInt(test.parentTime)); err != test.expected {
+			t.Errorf("parent time %d: expected %v, got %v", test.parentTime, test.expected, err)
+		}
+	}
+}
+
+func TestHomestead(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		NewContractAddress: func(_ *EVM, counter uint32) common.Address {
+			return common.BytesToAddress([]byte{0xc0, byte(counter)})
+		},
+		BlockNumber: new(big.Int),
+	}
+	// return(0, 100), the code deposit costs more than the supplied gas
+	code := common.Hex2Bytes("60646000f3")
+	create := func(homestead *bool) (uint64, error) {
+		evm := NewEVM(ctx, NoopStateDB{}, params.TestChainConfig, Config{Homestead: homestead})
+		_, _, gas, err := evm.Create(AccountRef(common.HexToAddress("1337")), code, 10000, nil)
+		return gas, err
+	}
+	homestead, frontier := true, false
+
+	for _, h := range []*bool{&homestead, nil} {
+		gas, err := create(h)
+		if err != ErrCodeStoreOutOfGas {
+			t.Errorf("expected %v, got %v", ErrCodeStoreOutOfGas, err)
+		}
+		if gas != 0 {
+			t.Errorf("expected all gas consumed, got %d left", gas)
+		}
+	}
+
+	gas, err := create(&frontier)
+	if err != ErrCodeStoreOutOfGas {
+		t.Errorf("expected %v, got %v", ErrCodeStoreOutOfGas, err)
+	}
+	if gas == 0 {
+		t.Error("expected gas left under frontier rules")
+	}
+}
+
+type countingStateDB struct {
+	NoopStateDB
+	code  []byte
+	reads int
+}
+
+func (db *countingStateDB) Exist(common.Address) bool { return true }
+func (db *countingStateDB) GetCode(common.Address) []byte {
+	db.reads++
+	return db.code
+}
+
+type mapViewCache map[common.Hash]struct {
+	ret     []byte
+	gasUsed uint64
+}
+
+func (c mapViewCache) Get(key common.Hash) ([]byte, uint64, bool) {
+	v, ok := c[key]
+	return v.ret, v.gasUsed, ok
+}
+
+func (c mapViewCache) Set(key common.Hash, ret []byte, gasUsed uint64) {
+	c[key] = struct {
+		ret     []byte
+		gasUsed uint64
+	}{ret, gasUsed}
+}
+
+func TestViewCache(t *testing.T) {
+	// mstore(0, calldataload(0)) return(0, 32)
+	db := &countingStateDB{code: common.Hex2Bytes("60003560005260206000f3")}
+	cache := make(mapViewCache)
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1)}, db, params.TestChainConfig, Config{ViewCache: cache, ReadOnly: true})
+
+	caller := AccountRef(common.HexToAddress("1337"))
+	addr := common.HexToAddress("1338")
+	input := common.LeftPadBytes([]byte{42}, 32)
+
+	ret1, gas1, err := evm.StaticCall(caller, addr, input, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret2, gas2, err := evm.StaticCall(caller, addr, input, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.reads != 1 {
+		t.Errorf("expected code read once, got %d", db.reads)
+	}
+	if !bytes.Equal(ret1, input) || !bytes.Equal(ret2, input) {
+		t.Errorf("unexpected return %x, %x", ret1, ret2)
+	}
+	if gas1 != gas2 {
+		t.Errorf("expected same gas left, got %d and %d", gas1, gas2)
+	}
+
+	// different input misses
+	if _, _, err := evm.StaticCall(caller, addr, common.LeftPadBytes([]byte{43}, 32), 100000); err != nil {
+		t.Fatal(err)
+	}
+	if db.reads != 2 {
+		t.Errorf("expected code read twice, got %d", db.reads)
+	}
+
+	// different caller misses
+	if _, _, err := evm.StaticCall(AccountRef(common.HexToAddress("1339")), addr, input, 100000); err != nil {
+		t.Fatal(err)
+	}
+	if db.reads != 3 {
+		t.Errorf("expected code read 3 times, got %d", db.reads)
+	}
+
+	// different state misses
+	evm.Reset(Context{BlockNumber: big.NewInt(1), StateRoot: common.HexToHash("1")}, db)
+	if _, _, err := evm.StaticCall(caller, addr, input, 100000); err != nil {
+		t.Fatal(err)
+	}
+	if db.reads != 4 {
+		t.Errorf("expected code read 4 times, got %d", db.reads)
+	}
+
+	// not consulted unless read-only
+	evm = NewEVM(Context{BlockNumber: big.NewInt(1)}, db, params.TestChainConfig, Config{ViewCache: cache})
+	if _, _, err := evm.StaticCall(caller, addr, input, 100000); err != nil {
+		t.Fatal(err)
+	}
+	if db.reads != 5 {
+		t.Errorf("expected code read 5 times, got %d", db.reads)
+	}
+}
+
+func TestInvalidJump(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	tests := []struct {
+		code     string
+		expected error
+	}{
+		{"6003565b00", nil},              // jump to JUMPDEST
+		{"600456605b00", ErrInvalidJump}, // jump to PUSH data
+		{"60ff56", ErrInvalidJump},       // jump past code end
+		{"60016005575b00", nil},          // jumpi to JUMPDEST
+		{"6001600357", ErrInvalidJump},   // jumpi to PUSH data
+		{"600160ff57", ErrInvalidJump},   // jumpi past code end
+		{"600060ff5700", nil},            // jumpi not taken
+	}
+	for _, test := range tests {
+		db := &countingStateDB{code: common.Hex2Bytes(test.code)}
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{})
+		_, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+		if errors.Cause(err) != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.code, test.expected, err)
+		}
+		if test.expected != nil && leftOverGas != 0 {
+			t.Errorf("%s: expected all gas consumed, got %d left", test.code, leftOverGas)
+		}
+		if test.expected == nil && leftOverGas == 0 {
+			t.Errorf("%s: expected gas left", test.code)
+		}
+	}
+}
+
+type returnDataTracer struct {
+	captured []byte
+}
+
+func (t *returnDataTracer) CaptureStart(common.Address, common.Address, bool, []byte, uint64, *big.Int) error {
+	return nil
+}
+func (t *returnDataTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if op == POP && depth == 1 {
+		t.captured = common.CopyBytes(env.LastReturnData())
+	}
+	return nil
+}
+func (t *returnDataTracer) CaptureFault(*EVM, uint64, OpCode, uint64, uint64, *Memory, *Stack, *Contract, int, error) error {
+	return nil
+}
+func (t *returnDataTracer) CaptureEnd([]byte, uint64, time.Duration, error) error { return nil }
+
+func TestLastReturnData(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	caller := thor.BytesToAddress([]byte{0x13, 0x38})
+	callee := thor.BytesToAddress([]byte{0x13, 0x39})
+	// call(gas, 0x1339, 0, 0, 0, 0, 0) pop stop
+	st.SetCode(caller, common.Hex2Bytes("600060006000600060006113395af15000"))
+	// mstore(0, 42) return(0, 32)
+	st.SetCode(callee, common.Hex2Bytes("602a60005260206000f3"))
+
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	tracer := &returnDataTracer{}
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(caller), nil, 100000, new(big.Int)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := common.LeftPadBytes([]byte{42}, 32); !bytes.Equal(tracer.captured, expected) {
+		t.Errorf("expected last return data %x, got %x", expected, tracer.captured)
+	}
+}
+
+type codeHashTracer struct {
+	returnDataTracer
+	hashes map[int]map[common.Hash]bool
+}
+
+func (t *codeHashTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	if t.hashes[depth] == nil {
+		t.hashes[depth] = make(map[common.Hash]bool)
+	}
+	t.hashes[depth][env.CurrentCodeHash()] = true
+	return nil
+}
+
+func TestCurrentCodeHash(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	caller := thor.BytesToAddress([]byte{0x13, 0x38})
+	callee := thor.BytesToAddress([]byte{0x13, 0x39})
+	// call(gas, 0x1339, 0, 0, 0, 0, 0) pop stop
+	st.SetCode(caller, common.Hex2Bytes("600060006000600060006113395af15000"))
+	// mstore(0, 42) return(0, 32)
+	st.SetCode(callee, common.Hex2Bytes("602a60005260206000f3"))
+
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	tracer := &codeHashTracer{hashes: make(map[int]map[common.Hash]bool)}
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(caller), nil, 100000, new(big.Int)); err != nil {
+		t.Fatal(err)
+	}
+
+	for depth, addr := range map[int]thor.Address{1: caller, 2: callee} {
+		expected := common.Hash(st.GetCodeHash(addr))
+		if len(tracer.hashes[depth]) != 1 || !tracer.hashes[depth][expected] {
+			t.Errorf("depth %d: expected code hash %x, got %v", depth, expected, tracer.hashes[depth])
+		}
+	}
+	if h := evm.CurrentCodeHash(); h != (common.Hash{}) {
+		t.Errorf("expected zero code hash after execution, got %x", h)
+	}
+}
+
+func TestWorstCaseGas(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	// getter: mstore(0, 42) return(0, 32)
+	db := &countingStateDB{code: common.Hex2Bytes("602a60005260206000f3")}
+	evm := NewEVM(ctx, db, params.TestChainConfig, Config{})
+	addr := common.HexToAddress("1338")
+
+	estimated, err := evm.WorstCaseGas(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), addr, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used := 100000 - leftOverGas; estimated < used || estimated > 2*used {
+		t.Errorf("expected estimate bounded by [%d, %d], got %d", used, 2*used, estimated)
+	}
+
+	db.code = nil
+	if estimated, _ := evm.WorstCaseGas(addr); estimated != 0 {
+		t.Errorf("expected 0 for empty code, got %d", estimated)
+	}
+
+	// sstore(0, 1) call(gas, 0x1338, 0, 0, 0, 0, 0) leaves the EVM untouched
+	db.code = common.Hex2Bytes("6001600055600060006000600060006113385af1")
+	evm = NewEVM(ctx, db, params.TestChainConfig, Config{EIP2200: true})
+	if _, err := evm.WorstCaseGas(addr); err != nil {
+		t.Fatal(err)
+	}
+	if evm.callGasTemp != 0 || evm.originalStates != nil {
+		t.Errorf("expected EVM untouched, got call gas %d, original states %v", evm.callGasTemp, evm.originalStates)
+	}
+}
+
+func TestPush0(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	disabled := false
+	tests := []struct {
+		code    string
+		eip3855 *bool
+		ret     []byte
+		gasUsed uint64
+		err     string
+	}{
+		// push0 not mstore(0) return(0, 32)
+		{"5f1960005260206000f3", nil, common.Hex2Bytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), 2 + 3 + 3 + 6 + 3 + 3, ""},
+		{"5f00", nil, nil, 2, ""},
+		{"5f00", &disabled, nil, 100000, ErrInvalidOpcode.Error()},
+	}
+	for _, test := range tests {
+		db := &countingStateDB{code: common.Hex2Bytes(test.code)}
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{EIP3855: test.eip3855})
+		ret, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+		if test.err == "" && err != nil || test.err != "" && (err == nil || errors.Cause(err).Error() != test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.code, test.err, err)
+		}
+		if !bytes.Equal(ret, test.ret) {
+			t.Errorf("%s: expected return %x, got %x", test.code, test.ret, ret)
+		}
+		if gasUsed := 100000 - leftOverGas; gasUsed != test.gasUsed {
+			t.Errorf("%s: expected gas used %d, got %d", test.code, test.gasUsed, gasUsed)
+		}
+	}
+}
+
+type fixedGasOracle struct{ price *big.Int }
+
+func (o fixedGasOracle) SuggestGasPrice(Context) *big.Int { return o.price }
+
+func TestGasOracle(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	// mstore(0, gasprice) return(0, 32)
+	db := &countingStateDB{code: common.Hex2Bytes("3a60005260206000f3")}
+	oracle := fixedGasOracle{big.NewInt(1000)}
+
+	tests := []struct {
+		gasPrice *big.Int
+		expected *big.Int
+	}{
+		{nil, big.NewInt(1000)},
+		{big.NewInt(10), big.NewInt(10)},
+	}
+	for _, test := range tests {
+		ctx.GasPrice = test.gasPrice
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{GasOracle: oracle})
+		ret, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price := new(big.Int).SetBytes(ret); price.Cmp(test.expected) != 0 {
+			t.Errorf("expected gas price %v, got %v", test.expected, price)
+		}
+	}
+}
+
+func TestCallTrivialCode(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	for _, code := range []string{"", "00"} {
+		db := &countingStateDB{code: common.Hex2Bytes(code)}
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{})
+		ret, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+		if err != nil {
+			t.Errorf("%q: expected success, got %v", code, err)
+		}
+		if len(ret) != 0 {
+			t.Errorf("%q: expected empty return, got %x", code, ret)
+		}
+		if leftOverGas != 100000 {
+			t.Errorf("%q: expected no gas consumed, got %d", code, 100000-leftOverGas)
+		}
+		if db.reads != 1 {
+			t.Errorf("%q: expected code read once, got %d", code, db.reads)
+		}
+	}
+}
+
+func BenchmarkCallTrivialCode(b *testing.B) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	bench := func(cfg Config) func(b *testing.B) {
+		return func(b *testing.B) {
+			evm := NewEVM(ctx, &countingStateDB{code: []byte{byte(STOP)}}, params.TestChainConfig, cfg)
+			caller, addr := AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338")
+			for i := 0; i < b.N; i++ {
+				evm.Call(caller, addr, nil, 100000, new(big.Int))
+			}
+		}
+	}
+	b.Run("fast", bench(Config{}))
+	// coverage map disables the fast path
+	b.Run("interpreter", bench(Config{CoverageMap: make(map[OpCode]uint64)}))
+}
+
+func TestEIP2200(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	addr := thor.BytesToAddress([]byte("addr"))
+	tests := []struct {
+		code    string
+		eip2200 bool
+		gasUsed uint64
+		refund  uint64
+	}{
+		// sstore(0, 1) sstore(0, 0)
+		{"60016000556000600055", true, 3*4 + 20000 + 800, 19200},
+		{"60016000556000600055", false, 3*4 + 20000 + 5000, 15000},
+		// sstore(0, 1) sstore(0, 1)
+		{"60016000556001600055", true, 3*4 + 20000 + 800, 0},
+	}
+	for _, test := range tests {
+		st, _ := state.New(thor.Bytes32{}, kv)
+		st.SetCode(addr, common.Hex2Bytes(test.code))
+		db := statedb.New(st)
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{EIP2200: test.eip2200})
+		_, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(addr), nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gasUsed := 100000 - leftOverGas; gasUsed != test.gasUsed {
+			t.Errorf("%s: expected gas used %d, got %d", test.code, test.gasUsed, gasUsed)
+		}
+		if refund := db.GetRefund(); refund != test.refund {
+			t.Errorf("%s: expected refund %d, got %d", test.code, test.refund, refund)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	setter := thor.BytesToAddress([]byte("setter"))
+	getter := thor.BytesToAddress([]byte("getter"))
+	// sstore(0, 1)
+	st.SetCode(setter, common.Hex2Bytes("6001600055"))
+	// mstore(0, 1) return(0, 32)
+	st.SetCode(getter, common.Hex2Bytes("600160005260206000f3"))
+
+	caller := AccountRef(common.HexToAddress("1337"))
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{ReadOnly: true})
+
+	if _, _, err := evm.Call(caller, common.Address(setter), nil, 100000, new(big.Int)); errors.Cause(err) != ErrWriteProtection {
+		t.Errorf("expected write protection error, got %v", err)
+	}
+	if got := st.GetStorage(setter, thor.Bytes32{}); !got.IsZero() {
+		t.Errorf("expected storage unchanged, got %v", got)
+	}
+	if _, _, err := evm.Call(caller, common.Address(getter), nil, 100000, big.NewInt(1)); errors.Cause(err) != ErrWriteProtection {
+		t.Errorf("expected write protection error on value transfer, got %v", err)
+	}
+	if _, _, _, err := evm.Create(caller, nil, 100000, new(big.Int)); errors.Cause(err) != ErrWriteProtection {
+		t.Errorf("expected write protection error on create, got %v", err)
+	}
+
+	ret, _, err := evm.Call(caller, common.Address(getter), nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 1 {
+		t.Errorf("expected getter to return 1, got %x", ret)
+	}
+}
+
+// arithCode loops 256 times doing: acc = (acc + i) * 3 % 0xffff, then returns acc.
+var arithCode = common.Hex2Bytes("6000610100" + "5b" + "808201600302" + "61ffff9006" + "9150" + "60019003" + "80600557" + "50" + "60005260206000f3")
+
+func TestIntPoolLimit(t *testing.T) {
+	acc := uint64(0)
+	for i := uint64(256); i > 0; i-- {
+		acc = (acc + i) * 3 % 0xffff
+	}
+	expected := common.BigToHash(new(big.Int).SetUint64(acc)).Bytes()
+
+	for _, limit := range []int{0, 1, -1} {
+		evm := NewEVM(Context{BlockNumber: new(big.Int)}, &countingStateDB{code: arithCode}, params.TestChainConfig, Config{IntPoolLimit: limit})
+		ret, _, err := evm.StaticCall(AccountRef(common.HexToAddress("1337")), common.HexToAddress("c0de"), nil, 1000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ret, expected) {
+			t.Errorf("limit %d: expected %x, got %x", limit, expected, ret)
+		}
+	}
+}
+
+func BenchmarkIntPool(b *testing.B) {
+	for _, limit := range []int{0, -1} {
+		name := "pooled"
+		if limit < 0 {
+			name = "unpooled"
+		}
+		b.Run(name, func(b *testing.B) {
+			db := &countingStateDB{code: arithCode}
+			evm := NewEVM(Context{BlockNumber: new(big.Int)}, db, params.TestChainConfig, Config{IntPoolLimit: limit})
+			caller := AccountRef(common.HexToAddress("1337"))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				evm.StaticCall(caller, common.HexToAddress("c0de"), nil, 1000000)
+			}
+		})
+	}
+}
+
+func TestInvalidOpcode(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	var invalidPCs []uint64
+	// push1(1) push1(2) invalid
+	db := &countingStateDB{code: common.Hex2Bytes("60016002fe")}
+	evm := NewEVM(ctx, db, params.TestChainConfig, Config{OnInvalid: func(pc uint64) { invalidPCs = append(invalidPCs, pc) }})
+
+	_, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+	if errors.Cause(err) != ErrInvalidOpcode {
+		t.Errorf("expected %v, got %v", ErrInvalidOpcode, err)
+	}
+	if execErr, ok := err.(*ExecError); !ok || execErr.Op != OpCode(0xfe) {
+		t.Errorf("expected exec error at opcode 0xfe, got %v", err)
+	}
+	if leftOverGas != 0 {
+		t.Errorf("expected all gas consumed, got %d left", leftOverGas)
+	}
+	if len(invalidPCs) != 1 || invalidPCs[0] != 4 {
+		t.Errorf("expected hook fired at pc 4, got %v", invalidPCs)
+	}
+}
+
+type hashingStateDB struct {
+	countingStateDB
+}
+
+func (db *hashingStateDB) GetCodeHash(common.Address) common.Hash {
+	return crypto.Keccak256Hash(db.code)
+}
+
+func TestPreloadCode(t *testing.T) {
+	// mstore(0, 42) return(0, 32)
+	db := &hashingStateDB{countingStateDB{code: common.Hex2Bytes("602a60005260206000f3")}}
+	evm := NewEVM(Context{BlockNumber: new(big.Int)}, db, params.TestChainConfig, Config{})
+	caller, addr := AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338")
+
+	evm.PreloadCode([]common.Address{addr, addr})
+	if db.reads != 1 {
+		t.Fatalf("expected code read once by preloading, got %d", db.reads)
+	}
+	for i := 0; i < 2; i++ {
+		evm.Reset(Context{BlockNumber: new(big.Int)}, db)
+		ret, _, err := evm.StaticCall(caller, addr, nil, 100000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ret, common.LeftPadBytes([]byte{42}, 32)) {
+			t.Errorf("unexpected return %x", ret)
+		}
+	}
+	if db.reads != 1 {
+		t.Errorf("expected preloaded code used, got %d reads", db.reads)
+	}
+
+	// changed code misses the cache
+	db.code = common.Hex2Bytes("602b60005260206000f3")
+	ret, _, err := evm.StaticCall(caller, addr, nil, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, common.LeftPadBytes([]byte{43}, 32)) {
+		t.Errorf("unexpected return %x", ret)
+	}
+	if db.reads != 2 {
+		t.Errorf("expected code read twice, got %d", db.reads)
+	}
+}
+
+func BenchmarkPreloadCode(b *testing.B) {
+	kv, _ := lvldb.NewMem()
+	addr := thor.BytesToAddress([]byte("contract"))
+	st, _ := state.New(thor.Bytes32{}, kv)
+	// mstore(0, 42) return(0, 32), padded to a typical contract size
+	st.SetCode(addr, append(common.Hex2Bytes("602a60005260206000f3"), make([]byte, 8192)...))
+	root, err := st.Stage().Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	bench := func(preload bool) func(b *testing.B) {
+		return func(b *testing.B) {
+			st, _ := state.New(root, kv)
+			evm := NewEVM(Context{BlockNumber: new(big.Int)}, statedb.New(st), params.TestChainConfig, Config{})
+			if preload {
+				evm.PreloadCode([]common.Address{common.Address(addr)})
+			}
+			caller := AccountRef(common.HexToAddress("1337"))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// each re-executed transaction runs on a fresh state
+				st, _ := state.New(root, kv)
+				evm.Reset(Context{BlockNumber: new(big.Int)}, statedb.New(st))
+				evm.StaticCall(caller, common.Address(addr), nil, 100000)
+			}
+		}
+	}
+	b.Run("cold", bench(false))
+	b.Run("preloaded", bench(true))
+}
+
+func TestMaxTransferValue(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	ctx := Context{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	origin := thor.BytesToAddress([]byte{0x13, 0x37})
+	caller := thor.BytesToAddress([]byte{0x13, 0x38})
+	callee := thor.BytesToAddress([]byte{0x13, 0x39})
+	// mstore(0, call(gas, 0x1339, 2, 0, 0, 0, 0)) return(0, 32)
+	st.SetCode(caller, common.Hex2Bytes("600060006000600060026113395af160005260206000f3"))
+	st.SetBalance(origin, big.NewInt(10))
+	st.SetBalance(caller, big.NewInt(10))
+
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{MaxTransferValue: big.NewInt(1)})
+
+	// top-level call
+	if _, _, err := evm.Call(AccountRef(common.Address(origin)), common.Address(caller), nil, 100000, big.NewInt(2)); err != ErrTransferTooLarge {
+		t.Errorf("expected %v, got %v", ErrTransferTooLarge, err)
+	}
+	// CALL with value above the limit fails
+	ret, _, err := evm.Call(AccountRef(common.Address(origin)), common.Address(caller), nil, 100000, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(ret).Sign() != 0 {
+		t.Errorf("expected inner call failed, got %x", ret)
+	}
+
+	for addr, expected := range map[thor.Address]int64{origin: 9, caller: 11, callee: 0} {
+		if balance := st.GetBalance(addr); balance.Int64() != expected {
+			t.Errorf("%v: expected balance %d, got %v", addr, expected, balance)
+		}
+	}
+}
+
+func TestSelfBalance(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	contract := thor.BytesToAddress([]byte("contract"))
+	// mstore(0, selfbalance()) return(0, 32)
+	st.SetCode(contract, common.Hex2Bytes("4760005260206000f3"))
+	st.SetBalance(contract, big.NewInt(12345))
+
+	evm := NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{EIP1884: true})
+	ret, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(contract), nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := new(big.Int).SetBytes(ret); balance.Int64() != 12345 {
+		t.Errorf("expected balance 12345, got %v", balance)
+	}
+	if gasUsed := 100000 - leftOverGas; gasUsed != 5+3+6+3+3 {
+		t.Errorf("expected gas used %d, got %d", 5+3+6+3+3, gasUsed)
+	}
+
+	// disabled by default
+	evm = NewEVM(ctx, statedb.New(st), params.TestChainConfig, Config{})
+	if _, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(contract), nil, 100000, new(big.Int)); errors.Cause(err) != ErrInvalidOpcode {
+		t.Errorf("expected %v, got %v", ErrInvalidOpcode, err)
+	}
+}
+
+type nonceStateDB struct {
+	NoopStateDB
+	nonces map[common.Address]uint64
+}
+
+func (db *nonceStateDB) GetNonce(addr common.Address) uint64 { return db.nonces[addr] }
+func (db *nonceStateDB) SetNonce(addr common.Address, nonce uint64) {
+	db.nonces[addr] = nonce
+}
+
+func TestResetNonceOnFailedCreate(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	caller := AccountRef(common.HexToAddress("1337"))
+
+	for _, test := range []struct {
+		reset    bool
+		initCode []byte
+		nonce    uint64
+	}{
+		{false, []byte{0xfe}, 1},
+		{true, []byte{0xfe}, 0},
+		{false, []byte{byte(STOP)}, 1},
+		{true, []byte{byte(STOP)}, 1},
+	} {
+		db := &nonceStateDB{nonces: make(map[common.Address]uint64)}
+		evm := NewEVM(ctx, db, params.TestChainConfig, Config{ResetNonceOnFailedCreate: test.reset})
+		_, _, _, err := evm.Create(caller, test.initCode, 100000, new(big.Int))
+		if failed := test.initCode[0] == 0xfe; failed != (err != nil) {
+			t.Errorf("%x: unexpected error %v", test.initCode, err)
+		}
+		if nonce := db.GetNonce(caller.Address()); nonce != test.nonce {
+			t.Errorf("%x, reset %v: expected nonce %d, got %d", test.initCode, test.reset, test.nonce, nonce)
+		}
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	disabled := false
+	newConfig := func() Config {
+		return Config{
+			MaxStorageWrites: 10,
+			CreateGas:        32000,
+			MinGasPrice:      big.NewInt(1),
+			EIP3855:          &disabled,
+			CustomOps: map[OpCode]CustomOpFunc{
+				OpCode(0xe0): nil,
+				OpCode(0xe1): nil,
+			},
+			Tracer: &returnDataTracer{},
+		}
+	}
+	cfg := newConfig()
+	hash := cfg.Hash()
+	if other := newConfig(); other.Hash() != hash {
+		t.Errorf("expected identical configs hash equally")
+	}
+
+	// hooks are not hashed
+	cfg.Tracer = nil
+	cfg.OnInvalid = func(uint64) {}
+	if cfg.Hash() != hash {
+		t.Errorf("expected hooks not affect hash")
+	}
+
+	changes := []func(*Config){
+		func(cfg *Config) { cfg.EIP2200 = true },
+		func(cfg *Config) { cfg.MaxStorageWrites = 11 },
+		func(cfg *Config) { cfg.MinGasPrice = nil },
+		func(cfg *Config) { cfg.MinGasPrice = big.NewInt(0) },
+		func(cfg *Config) { cfg.EIP3855 = nil },
+		func(cfg *Config) { cfg.EIP1884 = true },
+		func(cfg *Config) { cfg.EIP1052 = true },
+		func(cfg *Config) { cfg.EIP1153 = true },
+		func(cfg *Config) { cfg.Homestead = &disabled },
+		func(cfg *Config) { delete(cfg.CustomOps, OpCode(0xe1)) },
+	}
+	for i, change := range changes {
+		cfg := newConfig()
+		change(&cfg)
+		if cfg.Hash() == hash {
+			t.Errorf("change %d: expected hash altered", i)
+		}
+	}
+}
+
+func TestVerifyPure(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	getter := thor.BytesToAddress([]byte("getter"))
+	setter := thor.BytesToAddress([]byte("setter"))
+	emitter := thor.BytesToAddress([]byte("emitter"))
+	reverter := thor.BytesToAddress([]byte("reverter"))
+	// mstore(0, sload(0)) return(0, 32)
+	st.SetCode(getter, common.Hex2Bytes("60005460005260206000f3"))
+	// sstore(0, 1)
+	st.SetCode(setter, common.Hex2Bytes("6001600055"))
+	// log0(0, 0)
+	st.SetCode(emitter, common.Hex2Bytes("60006000a0"))
+	// revert(0, 0)
+	st.SetCode(reverter, common.Hex2Bytes("60006000fd"))
+
+	evm := NewEVM(Context{BlockNumber: new(big.Int)}, statedb.New(st), params.TestChainConfig, Config{})
+
+	tests := []struct {
+		addr     thor.Address
+		expected bool
+	}{
+		{getter, true},
+		{setter, false},
+		{emitter, false},
+	}
+	for _, test := range tests {
+		pure, err := evm.VerifyPure(common.Address(test.addr), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pure != test.expected {
+			t.Errorf("%v: expected pure %v, got %v", test.addr, test.expected, pure)
+		}
+	}
+	if got := st.GetStorage(setter, thor.Bytes32{}); !got.IsZero() {
+		t.Errorf("expected storage unchanged, got %v", got)
+	}
+
+	if _, err := evm.VerifyPure(common.Address(reverter), nil); err != errExecutionReverted {
+		t.Errorf("expected %v, got %v", errExecutionReverted, err)
+	}
+}
+
+func TestExtCodeHash(t *testing.T) {
+	kv, _ := lvldb.NewMem()
+	st, _ := state.New(thor.Bytes32{}, kv)
+
+	target := thor.BytesToAddress([]byte("target"))
+	st.SetCode(target, common.Hex2Bytes("602a60005260206000f3"))
+	funded := thor.BytesToAddress([]byte("funded"))
+	st.SetBalance(funded, big.NewInt(1))
+
+	caller := thor.BytesToAddress([]byte("caller"))
+
+	evm := NewEVM(Context{BlockNumber: new(big.Int)}, statedb.New(st), params.TestChainConfig, Config{EIP1052: true})
+	extCodeHash := func(addr common.Address) common.Hash {
+		// mstore(0, extcodehash(addr)) return(0, 32)
+		st.SetCode(caller, common.Hex2Bytes("73"+common.Bytes2Hex(addr.Bytes())+"3f60005260206000f3"))
+		ret, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(caller), nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return common.BytesToHash(ret)
+	}
+
+	tests := []struct {
+		addr     common.Address
+		expected common.Hash
+	}{
+		// precompile
+		{common.BytesToAddress([]byte{1}), crypto.Keccak256Hash(nil)},
+		{common.Address(target), common.Hash(st.GetCodeHash(target))},
+		// existing without code
+		{common.Address(funded), crypto.Keccak256Hash(nil)},
+		// not existing
+		{common.HexToAddress("dead"), common.Hash{}},
+	}
+	for _, test := range tests {
+		if h := extCodeHash(test.addr); h != test.expected {
+			t.Errorf("%x: expected %x, got %x", test.addr, test.expected, h)
+		}
+	}
+
+	// disabled by default
+	evm = NewEVM(Context{BlockNumber: new(big.Int)}, statedb.New(st), params.TestChainConfig, Config{})
+	if _, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.Address(caller), nil, 100000, new(big.Int)); errors.Cause(err) != ErrInvalidOpcode {
+		t.Errorf("expected %v, got %v", ErrInvalidOpcode, err)
+	}
+}
+
+func TestZeroAddressHandler(t *testing.T) {
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	// mstore(0, 42) return(0, 32)
+	db := &countingStateDB{code: common.Hex2Bytes("602a60005260206000f3")}
+	echo := func(input []byte) ([]byte, error) { return input, nil }
+	evm := NewEVM(ctx, db, params.TestChainConfig, Config{ZeroAddressHandler: echo})
+	caller := AccountRef(common.HexToAddress("1337"))
+	input := []byte("echo")
+
+	ret, leftOverGas, err := evm.Call(caller, common.Address{}, input, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, input) {
+		t.Errorf("expected echo %x, got %x", input, ret)
+	}
+	if leftOverGas != 100000 {
+		t.Errorf("expected no gas consumed, got %d", 100000-leftOverGas)
+	}
+	if db.reads != 0 {
+		t.Errorf("expected no code read, got %d", db.reads)
+	}
+
+	// other addresses execute normally
+	ret, _, err = evm.Call(caller, common.HexToAddress("1338"), input, 100000, new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, common.LeftPadBytes([]byte{42}, 32)) {
+		t.Errorf("unexpected return %x", ret)
+	}
+
+	// errors consume all gas
+	fail := errors.New("rejected")
+	evm = NewEVM(ctx, db, params.TestChainConfig, Config{ZeroAddressHandler: func([]byte) ([]byte, error) { return nil, fail }})
+	if _, leftOverGas, err := evm.Call(caller, common.Address{}, input, 100000, new(big.Int)); err != fail || leftOverGas != 0 {
+		t.Errorf("expected %v with all gas consumed, got %v with %d left", fail, err, leftOverGas)
+	}
+}
+
+func TestFeeSplit(t *testing.T) {
+	tests := []struct {
+		baseFee, gasPrice *big.Int
+		burned, tip       int64
+	}{
+		{big.NewInt(7), big.NewInt(10), 7 * 21000, 3 * 21000},
+		{big.NewInt(10), big.NewInt(10), 10 * 21000, 0},
+		// capped to gas price
+		{big.NewInt(12), big.NewInt(10), 10 * 21000, 0},
+		// no base fee
+		{nil, big.NewInt(10), 0, 10 * 21000},
+	}
+	for _, test := range tests {
+		evm := NewEVM(Context{BlockNumber: new(big.Int), BaseFee: test.baseFee, GasPrice: test.gasPrice}, NoopStateDB{}, params.TestChainConfig, Config{})
+		burned, tip := evm.FeeSplit(21000)
+		if burned.Int64() != test.burned || tip.Int64() != test.tip {
+			t.Errorf("base fee %v, gas price %v: expected (%d, %d), got (%v, %v)", test.baseFee, test.gasPrice, test.burned, test.tip, burned, tip)
+		}
+		if total := new(big.Int).Add(burned, tip); total.Cmp(new(big.Int).Mul(test.gasPrice, big.NewInt(21000))) != 0 {
+			t.Errorf("expected fee fully split, got %v", total)
+		}
+	}
+}
+
+func TestExtractSelectors(t *testing.T) {
+	tests := []struct {
+		code      string
+		selectors [][4]byte
+	}{
+		// solidity 0.4.18, contract TestSuicide { function testSuicide() public { selfdestruct(msg.sender); } }
+		{
+			"608060405260043610603f576000357c0100000000000000000000000000000000000000000000000000000000900463ffffffff168063085da1b3146044575b600080fd5b348015604f57600080fd5b5060566058565b005b3373ffffffffffffffffffffffffffffffffffffffff16ff00a165627a7a723058204cb70b653a3d1821e00e6ade869638e80fa99719931c9fa045cec2189d94086f0029",
+			[][4]byte{{0x08, 0x5d, 0xa1, 0xb3}},
+		},
+		// balanceOf(address) and transfer(address,uint256), with DUP2 before EQ and a duplicated entry
+		{
+			"60e060020a600035048063" + "70a08231" + "1461003057" + "8063" + "a9059cbb" + "811461004057" + "8063" + "70a08231" + "1461003057" + "00",
+			[][4]byte{{0x70, 0xa0, 0x82, 0x31}, {0xa9, 0x05, 0x9c, 0xbb}},
+		},
+		// PUSH4 hidden in push data
+		{"7f63aabbccdd14600057000000000000000000000000000000000000000000000000", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		db := &countingStateDB{code: common.Hex2Bytes(test.code)}
+		evm := NewEVM(Context{BlockNumber: new(big.Int)}, db, params.TestChainConfig, Config{})
+		if selectors := evm.ExtractSelectors(common.HexToAddress("1338")); !reflect.DeepEqual(selectors, test.selectors) {
+			t.Errorf("%s: expected %x, got %x", test.code, test.selectors, selectors)
+		}
+	}
+}
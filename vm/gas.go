@@ -35,6 +35,12 @@ const (
 	GasContractByte uint64 = 200
 )
 
+// GasExtCodeHash is the gas of EXTCODEHASH, see EIP-1052.
+const GasExtCodeHash uint64 = 400
+
+// GasTransient is the gas of TLOAD and TSTORE, see EIP-1153.
+const GasTransient uint64 = 100
+
 // calcGas returns the actual gas cost of the call.
 //
 // The cost of gas was changed during the homestead price change HF. To allow for EIP150
@@ -22,6 +22,16 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// MemoryGasCost returns the total gas of memory sized in words, which has
+// a linear and a quadratic term. words must not exceed 0x7ffffffff, or the
+// square overflows.
+func MemoryGasCost(words uint64) uint64 {
+	square := words * words
+	linCoef := words * params.MemoryGas
+	quadCoef := square / params.QuadCoeffDiv
+	return linCoef + quadCoef
+}
+
 // memoryGasCosts calculates the quadratic gas for memory expansion. It does so
 // only for the memory region that is expanded, not the total memory.
 func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
@@ -44,10 +54,7 @@ func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
 	newMemSize = newMemSizeWords * 32
 
 	if newMemSize > uint64(mem.Len()) {
-		square := newMemSizeWords * newMemSizeWords
-		linCoef := newMemSizeWords * params.MemoryGas
-		quadCoef := square / params.QuadCoeffDiv
-		newTotalFee := linCoef + quadCoef
+		newTotalFee := MemoryGasCost(newMemSizeWords)
 
 		fee := newTotalFee - mem.lastGasCost
 		mem.lastGasCost = newTotalFee
@@ -115,52 +122,113 @@ func gasReturnDataCopy(gt params.GasTable, evm *EVM, contract *Contract, stack *
 	return gas, nil
 }
 
+// gas constants of EIP-2200 net gas metering.
+const (
+	sloadGasEIP2200        uint64 = 800
+	sstoreSentryGasEIP2200 uint64 = 2300
+)
+
 func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	var (
-		y, x = stack.Back(1), stack.Back(0)
-		val  = evm.StateDB.GetState(contract.Address(), common.BigToHash(x))
+		y, x     = stack.Back(1), stack.Back(0)
+		key      = common.BigToHash(x)
+		current  = evm.StateDB.GetState(contract.Address(), key)
+		original = current
 	)
-	// This checks for 3 scenario's and calculates gas accordingly
-	// 1. From a zero-value address to a non-zero value         (NEW VALUE)
-	// 2. From a non-zero value address to a zero-value address (DELETE)
-	// 3. From a non-zero to a non-zero                         (CHANGE)
-	if val == (common.Hash{}) && y.Sign() != 0 {
-		// 0 => non 0
-		return params.SstoreSetGas, nil
-	} else if val != (common.Hash{}) && y.Sign() == 0 {
-		// non 0 => 0
-		evm.StateDB.AddRefund(params.SstoreRefundGas)
-		return params.SstoreClearGas, nil
-	} else {
+	if evm.vmConfig.EIP2200 {
+		// fail if not enough gas left for the reentrancy sentry
+		if contract.Gas <= sstoreSentryGasEIP2200 {
+			return 0, ErrOutOfGas
+		}
+		original = evm.originalState(contract.Address(), key, current)
+	}
+	gas, refund := SstoreGas(original, current, common.BigToHash(y), evm.vmConfig)
+	if refund > 0 {
+		evm.StateDB.AddRefund(uint64(refund))
+	} else if refund < 0 {
+		evm.StateDB.SubRefund(uint64(-refund))
+	}
+	return gas, nil
+}
+
+// SstoreGas returns gas and refund of SSTORE, which sets storage slot of the value original
+// at the beginning of execution, and current at present, to new.
+// The net gas metering of EIP-2200 applies if cfg.EIP2200 set, otherwise original is ignored.
+func SstoreGas(original, current, new common.Hash, cfg Config) (gas uint64, refund int64) {
+	if !cfg.EIP2200 {
+		// This checks for 3 scenario's and calculates gas accordingly
+		// 1. From a zero-value address to a non-zero value         (NEW VALUE)
+		// 2. From a non-zero value address to a zero-value address (DELETE)
+		// 3. From a non-zero to a non-zero                         (CHANGE)
+		if current == (common.Hash{}) && new != (common.Hash{}) {
+			// 0 => non 0
+			return params.SstoreSetGas, 0
+		} else if current != (common.Hash{}) && new == (common.Hash{}) {
+			// non 0 => 0
+			return params.SstoreClearGas, int64(params.SstoreRefundGas)
+		}
 		// non 0 => non 0 (or 0 => 0)
-		return params.SstoreResetGas, nil
+		return params.SstoreResetGas, 0
+	}
+
+	// no-op
+	if current == new {
+		return sloadGasEIP2200, 0
+	}
+	// clean slot
+	if original == current {
+		if original == (common.Hash{}) {
+			return params.SstoreSetGas, 0
+		}
+		if new == (common.Hash{}) {
+			refund = int64(params.SstoreRefundGas)
+		}
+		return params.SstoreResetGas, refund
+	}
+	// dirty slot
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) {
+			refund -= int64(params.SstoreRefundGas)
+		} else if new == (common.Hash{}) {
+			refund += int64(params.SstoreRefundGas)
+		}
+	}
+	if original == new {
+		if original == (common.Hash{}) {
+			refund += int64(params.SstoreSetGas - sloadGasEIP2200)
+		} else {
+			refund += int64(params.SstoreResetGas - sloadGasEIP2200)
+		}
+	}
+	return sloadGasEIP2200, refund
+}
+
+// LogGas returns the gas charged by LOG0-LOG4 with the given number of topics
+// and data length, excluding memory expansion. It saturates at math.MaxUint64.
+func LogGas(topics int, dataLen int) uint64 {
+	gas := params.LogGas + uint64(topics)*params.LogTopicGas
+	dataGas, overflow := math.SafeMul(uint64(dataLen), params.LogDataGas)
+	if overflow {
+		return math.MaxUint64
+	}
+	if gas, overflow = math.SafeAdd(gas, dataGas); overflow {
+		return math.MaxUint64
 	}
+	return gas
 }
 
 func makeGasLog(n uint64) gasFunc {
 	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-		requestedSize, overflow := bigUint64(stack.Back(1))
-		if overflow {
-			return 0, errGasUintOverflow
-		}
-
 		gas, err := memoryGasCost(mem, memorySize)
 		if err != nil {
 			return 0, err
 		}
-
-		if gas, overflow = math.SafeAdd(gas, params.LogGas); overflow {
-			return 0, errGasUintOverflow
-		}
-		if gas, overflow = math.SafeAdd(gas, n*params.LogTopicGas); overflow {
-			return 0, errGasUintOverflow
-		}
-
-		var memorySizeGas uint64
-		if memorySizeGas, overflow = math.SafeMul(requestedSize, params.LogDataGas); overflow {
+		// the data size is bounded by the memory expansion checked above
+		requestedSize, overflow := bigUint64(stack.Back(1))
+		if overflow {
 			return 0, errGasUintOverflow
 		}
-		if gas, overflow = math.SafeAdd(gas, memorySizeGas); overflow {
+		if gas, overflow = math.SafeAdd(gas, LogGas(int(n), int(requestedSize))); overflow {
 			return 0, errGasUintOverflow
 		}
 		return gas, nil
@@ -277,13 +345,35 @@ func gasMStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	return gas, nil
 }
 
-func gasCreate(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	var overflow bool
+// gasCreate returns the gas charged by CREATE for init code of the given length,
+// excluding memory expansion. It saturates at math.MaxUint64.
+func gasCreate(codeLen int, cfg Config) uint64 {
+	base := cfg.CreateGas
+	if base == 0 {
+		base = params.CreateGas
+	}
+	wordGas, overflow := math.SafeMul(toWordSize(uint64(codeLen)), cfg.InitCodeWordGas)
+	if overflow {
+		return math.MaxUint64
+	}
+	gas, overflow := math.SafeAdd(base, wordGas)
+	if overflow {
+		return math.MaxUint64
+	}
+	return gas
+}
+
+func gasCreateOp(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	gas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
 		return 0, err
 	}
-	if gas, overflow = math.SafeAdd(gas, params.CreateGas); overflow {
+	// the init code size is bounded by the memory expansion checked above
+	codeLen, overflow := bigUint64(stack.Back(2))
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	if gas, overflow = math.SafeAdd(gas, gasCreate(int(codeLen), evm.vmConfig)); overflow {
 		return 0, errGasUintOverflow
 	}
 	return gas, nil
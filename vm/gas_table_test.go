@@ -16,7 +16,14 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+)
 
 func TestMemoryGasCost(t *testing.T) {
 	//size := uint64(math.MaxUint64 - 64)
@@ -34,3 +41,184 @@ func TestMemoryGasCost(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestMemoryGasCostQuadratic(t *testing.T) {
+	tests := []struct {
+		words uint64
+		gas   uint64
+	}{
+		// MemoryGas * words + words^2 / QuadCoeffDiv
+		{1, 3},
+		{100, 300 + 19},
+		{10000, 30000 + 195312},
+	}
+	for _, test := range tests {
+		if gas := MemoryGasCost(test.words); gas != test.gas {
+			t.Errorf("%d words: expected gas %d, got %d", test.words, test.gas, gas)
+		}
+	}
+	// 100x words costs far more than 100x gas
+	if MemoryGasCost(10000) <= 100*MemoryGasCost(100) {
+		t.Error("expected quadratic growth")
+	}
+
+	// expansion is charged by the difference of total costs
+	mem := NewMemory()
+	if gas, _ := memoryGasCost(mem, 100*32); gas != MemoryGasCost(100) {
+		t.Errorf("expected %d, got %d", MemoryGasCost(100), gas)
+	}
+	mem.Resize(100 * 32)
+	if gas, _ := memoryGasCost(mem, 10000*32); gas != MemoryGasCost(10000)-MemoryGasCost(100) {
+		t.Errorf("expected %d, got %d", MemoryGasCost(10000)-MemoryGasCost(100), gas)
+	}
+}
+
+func TestGasCreate(t *testing.T) {
+	// base component
+	if v := gasCreate(0, Config{}); v != params.CreateGas {
+		t.Errorf("Expected: %d, got %d", params.CreateGas, v)
+	}
+	if v := gasCreate(100, Config{}); v != params.CreateGas {
+		t.Errorf("Expected: %d, got %d", params.CreateGas, v)
+	}
+	if v := gasCreate(0, Config{CreateGas: 100}); v != 100 {
+		t.Errorf("Expected: 100, got %d", v)
+	}
+
+	// word rounding component
+	tests := []struct {
+		codeLen int
+		words   uint64
+	}{
+		{0, 0},
+		{1, 1},
+		{31, 1},
+		{32, 1},
+		{33, 2},
+		{64, 2},
+		{65, 3},
+	}
+	for _, test := range tests {
+		cfg := Config{CreateGas: 1, InitCodeWordGas: 2}
+		if v := gasCreate(test.codeLen, cfg); v != 1+test.words*2 {
+			t.Errorf("codeLen %d: expected %d, got %d", test.codeLen, 1+test.words*2, v)
+		}
+	}
+
+	// saturates on overflow
+	if v := gasCreate(64, Config{InitCodeWordGas: math.MaxUint64}); v != math.MaxUint64 {
+		t.Errorf("Expected: %d, got %d", uint64(math.MaxUint64), v)
+	}
+}
+
+func TestGasSha3(t *testing.T) {
+	tests := []struct {
+		size uint64
+		gas  uint64
+	}{
+		// Sha3Gas + Sha3WordGas * words + MemoryGas * words + words^2 / QuadCoeffDiv
+		{0, 30},
+		{32, 30 + 6 + 3},
+		{1024, 30 + 6*32 + 3*32 + 32*32/512},
+	}
+
+	var last uint64
+	for i, test := range tests {
+		stack := newstack()
+		stack.push(new(big.Int).SetUint64(test.size))
+		stack.push(new(big.Int))
+		memorySize := toWordSize(memorySha3(stack).Uint64()) * 32
+
+		gas, err := gasSha3(params.GasTable{}, nil, nil, stack, NewMemory(), memorySize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gas != test.gas {
+			t.Errorf("size %d: expected gas %d, got %d", test.size, test.gas, gas)
+		}
+		if i > 0 && gas <= last {
+			t.Errorf("size %d: gas %d not increased from %d", test.size, gas, last)
+		}
+		last = gas
+	}
+}
+
+func TestSstoreGas(t *testing.T) {
+	var (
+		zero = common.Hash{}
+		one  = common.BigToHash(big.NewInt(1))
+		two  = common.BigToHash(big.NewInt(2))
+	)
+	tests := []struct {
+		original, current, new common.Hash
+		eip2200                bool
+		gas                    uint64
+		refund                 int64
+	}{
+		// no-op
+		{one, one, one, true, 800, 0},
+		{one, one, one, false, params.SstoreResetGas, 0},
+		// fresh set
+		{zero, zero, one, true, params.SstoreSetGas, 0},
+		{zero, zero, one, false, params.SstoreSetGas, 0},
+		// clean clear
+		{one, one, zero, true, params.SstoreResetGas, int64(params.SstoreRefundGas)},
+		// dirty reset
+		{one, two, one, true, 800, int64(params.SstoreResetGas - 800)},
+		{zero, one, zero, true, 800, int64(params.SstoreSetGas - 800)},
+		{one, zero, one, true, 800, int64(params.SstoreResetGas-800) - int64(params.SstoreRefundGas)},
+		{one, two, zero, true, 800, int64(params.SstoreRefundGas)},
+		// original ignored without EIP-2200
+		{one, two, one, false, params.SstoreResetGas, 0},
+	}
+	for i, test := range tests {
+		gas, refund := SstoreGas(test.original, test.current, test.new, Config{EIP2200: test.eip2200})
+		if gas != test.gas || refund != test.refund {
+			t.Errorf("test %d: expected (%d, %d), got (%d, %d)", i, test.gas, test.refund, gas, refund)
+		}
+	}
+}
+
+func TestLogGas(t *testing.T) {
+	tests := []struct {
+		topics, dataLen int
+		gas             uint64
+	}{
+		{0, 0, params.LogGas},
+		{4, 0, params.LogGas + 4*params.LogTopicGas},
+		{0, 32, params.LogGas + 32*params.LogDataGas},
+		{4, 32, params.LogGas + 4*params.LogTopicGas + 32*params.LogDataGas},
+	}
+	for _, test := range tests {
+		if gas := LogGas(test.topics, test.dataLen); gas != test.gas {
+			t.Errorf("topics %d, data %d: expected %d, got %d", test.topics, test.dataLen, test.gas, gas)
+		}
+	}
+
+	// saturates on overflow
+	if gas := LogGas(0, math.MaxInt64); gas != math.MaxUint64 {
+		t.Errorf("Expected: %d, got %d", uint64(math.MaxUint64), gas)
+	}
+
+	// LOG0 and LOG4 of identical data
+	exec := func(code string) uint64 {
+		db := &countingStateDB{code: common.Hex2Bytes(code)}
+		evm := NewEVM(Context{BlockNumber: new(big.Int)}, db, params.TestChainConfig, Config{})
+		_, leftOverGas, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress("1338"), nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return 100000 - leftOverGas
+	}
+	// log0(0, 32)
+	log0 := exec("60206000a0")
+	// log4(0, 32, 0, 0, 0, 0)
+	log4 := exec("600060006000600060206000a4")
+	// 4 extra pushes of topics
+	if diff := log4 - log0; diff != 4*GasFastestStep+4*params.LogTopicGas {
+		t.Errorf("expected LOG4 costs %d more than LOG0, got %d", 4*GasFastestStep+4*params.LogTopicGas, diff)
+	}
+	if expected := 2*GasFastestStep + 3 + LogGas(0, 32); log0 != expected {
+		t.Errorf("expected LOG0 gas %d, got %d", expected, log0)
+	}
+}
@@ -17,8 +17,8 @@
 package vm
 
 import (
+	"encoding/binary"
 	"errors"
-	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -31,7 +31,6 @@ import (
 var (
 	bigZero                  = new(big.Int)
 	tt255                    = math.BigPow(2, 255)
-	errWriteProtection       = errors.New("evm: write protection")
 	errReturnDataOutOfBounds = errors.New("evm: return data out of bounds")
 	errExecutionReverted     = errors.New("evm: execution reverted")
 	errMaxCodeSizeExceeded   = errors.New("evm: max code size exceeded")
@@ -395,6 +394,11 @@ func opBalance(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *
 	return nil, nil
 }
 
+func opSelfBalance(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	stack.push(evm.interpreter.intPool.get().Set(evm.StateDB.GetBalance(contract.Address())))
+	return nil, nil
+}
+
 func opOrigin(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	stack.push(evm.Origin.Big())
 	return nil, nil
@@ -462,6 +466,25 @@ func opExtCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, sta
 	return nil, nil
 }
 
+func opExtCodeHash(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek()
+	addr := common.BigToAddress(slot)
+	switch {
+	case evm.isPrecompile(addr):
+		// precompiles are considered existing with empty code
+		slot.Set(emptyCodeHash.Big())
+	case evm.StateDB.Empty(addr):
+		slot.SetUint64(0)
+	default:
+		hash := evm.StateDB.GetCodeHash(addr)
+		if hash == (common.Hash{}) {
+			hash = emptyCodeHash
+		}
+		slot.Set(hash.Big())
+	}
+	return nil, nil
+}
+
 func opCodeSize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	l := evm.interpreter.intPool.get().SetInt64(int64(len(contract.Code)))
 	stack.push(l)
@@ -530,10 +553,22 @@ func opNumber(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 }
 
 func opDifficulty(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	if evm.Difficulty.Sign() == 0 && evm.RandaoSeed != 0 {
+		stack.push(evm.interpreter.intPool.get().SetBytes(randaoValue(evm.RandaoSeed, evm.BlockNumber)))
+		return nil, nil
+	}
 	stack.push(math.U256(evm.interpreter.intPool.get().Set(evm.Difficulty)))
 	return nil, nil
 }
 
+// randaoValue derives a pseudo random value from the seed and block number.
+func randaoValue(seed uint64, blockNumber *big.Int) []byte {
+	var data [40]byte
+	binary.BigEndian.PutUint64(data[:8], seed)
+	math.ReadBits(blockNumber, data[8:])
+	return crypto.Keccak256(data[:])
+}
+
 func opGasLimit(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	stack.push(math.U256(evm.interpreter.intPool.get().SetUint64(evm.GasLimit)))
 	return nil, nil
@@ -577,6 +612,12 @@ func opSload(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *St
 }
 
 func opSstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	if max := evm.vmConfig.MaxStorageWrites; max > 0 {
+		if evm.storageWrites >= max {
+			return nil, ErrStorageWriteLimit
+		}
+		evm.storageWrites++
+	}
 	loc := common.BigToHash(stack.pop())
 	val := stack.pop()
 	evm.StateDB.SetState(contract.Address(), loc, common.BigToHash(val))
@@ -585,11 +626,26 @@ func opSstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 	return nil, nil
 }
 
+func opTload(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	loc := stack.peek()
+	val := evm.StateDB.GetTransientState(contract.Address(), common.BigToHash(loc))
+	loc.SetBytes(val.Bytes())
+	return nil, nil
+}
+
+func opTstore(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	loc := common.BigToHash(stack.pop())
+	val := stack.pop()
+	evm.StateDB.SetTransientState(contract.Address(), loc, common.BigToHash(val))
+
+	evm.interpreter.intPool.put(val)
+	return nil, nil
+}
+
 func opJump(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	pos := stack.pop()
 	if !contract.jumpdests.has(contract.CodeHash, contract.Code, pos) {
-		nop := contract.GetOp(pos.Uint64())
-		return nil, fmt.Errorf("invalid jump destination (%v) %v", nop, pos)
+		return nil, ErrInvalidJump
 	}
 	*pc = pos.Uint64()
 
@@ -601,8 +657,7 @@ func opJumpi(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *St
 	pos, cond := stack.pop(), stack.pop()
 	if cond.Sign() != 0 {
 		if !contract.jumpdests.has(contract.CodeHash, contract.Code, pos) {
-			nop := contract.GetOp(pos.Uint64())
-			return nil, fmt.Errorf("invalid jump destination (%v) %v", nop, pos)
+			return nil, ErrInvalidJump
 		}
 		*pc = pos.Uint64()
 	} else {
@@ -627,6 +682,11 @@ func opMsize(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *St
 	return nil, nil
 }
 
+func opPush0(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	stack.push(evm.interpreter.intPool.getZero())
+	return nil, nil
+}
+
 func opGas(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	stack.push(evm.interpreter.intPool.get().SetUint64(contract.Gas))
 	return nil, nil
@@ -649,7 +709,7 @@ func opCreate(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *S
 	// homestead we must check for CodeStoreOutOfGasError (homestead only
 	// rule) and treat as an error, if the ruleset is frontier we must
 	// ignore this error and pretend the operation was successful.
-	if evm.ChainConfig().IsHomestead(evm.BlockNumber) && suberr == ErrCodeStoreOutOfGas {
+	if evm.isHomestead() && suberr == ErrCodeStoreOutOfGas {
 		stack.push(evm.interpreter.intPool.getZero())
 	} else if suberr != nil && suberr != ErrCodeStoreOutOfGas {
 		stack.push(evm.interpreter.intPool.getZero())
@@ -809,6 +869,12 @@ func opSuicide(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *
 // make log instruction function
 func makeLog(size int) executionFunc {
 	return func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+		if max := evm.vmConfig.MaxLogs; max > 0 {
+			if evm.logCount >= max {
+				return nil, ErrLogLimit
+			}
+			evm.logCount++
+		}
 		topics := make([]common.Hash, size)
 		mStart, mSize := stack.pop(), stack.pop()
 		for i := 0; i < size; i++ {
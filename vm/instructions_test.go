@@ -425,3 +425,77 @@ func BenchmarkOpIsZero(b *testing.B) {
 	x := "FBCDEF090807060504030201ffffffffFBCDEF090807060504030201ffffffff"
 	opBenchmark(b, opIszero, x)
 }
+
+func TestCodeCopy(t *testing.T) {
+	var (
+		env      = NewEVM(Context{}, nil, params.TestChainConfig, Config{})
+		pc       = uint64(0)
+		code     = common.Hex2Bytes("6001600255")
+		contract = NewContract(AccountRef(common.Address{}), AccountRef(common.Address{}), new(big.Int), 0)
+	)
+	contract.SetCallCode(nil, common.Hash{}, code)
+
+	tests := []struct {
+		offset   int64
+		size     int64
+		expected string
+	}{
+		{0, 5, "6001600255"},
+		{0, 8, "6001600255000000"},
+		{3, 4, "02550000"},
+		{5, 2, "0000"},
+		{100, 3, "000000"},
+	}
+	for i, test := range tests {
+		stack := newstack()
+		memory := NewMemory()
+		memory.Resize(uint64(test.size))
+		stack.push(big.NewInt(test.size))
+		stack.push(big.NewInt(test.offset))
+		stack.push(big.NewInt(0))
+		opCodeCopy(&pc, env, contract, memory, stack)
+		if actual := common.Bytes2Hex(memory.Data()); actual != test.expected {
+			t.Errorf("Testcase %d, expected %s, got %s", i, test.expected, actual)
+		}
+	}
+
+	stack := newstack()
+	opCodeSize(&pc, env, contract, nil, stack)
+	if size := stack.pop().Int64(); size != int64(len(code)) {
+		t.Errorf("expected code size %d, got %d", len(code), size)
+	}
+}
+
+func TestDivByZero(t *testing.T) {
+	// the divisor is x, it's pushed first so the dividend y is on top
+	zero := "0000000000000000000000000000000000000000000000000000000000000000"
+	tests := []twoOperandTest{
+		{zero, "0000000000000000000000000000000000000000000000000000000000000005", zero},
+		{zero, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", zero},
+		{zero, "8000000000000000000000000000000000000000000000000000000000000000", zero},
+		{zero, zero, zero},
+	}
+	for _, op := range []struct {
+		name string
+		fn   func(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error)
+	}{
+		{"DIV", opDiv},
+		{"SDIV", opSdiv},
+		{"MOD", opMod},
+		{"SMOD", opSmod},
+	} {
+		t.Run(op.name, func(t *testing.T) {
+			testTwoOperandOp(t, tests, op.fn)
+		})
+	}
+}
+
+func TestSdivOverflow(t *testing.T) {
+	tests := []twoOperandTest{
+		// MIN_INT / -1
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "8000000000000000000000000000000000000000000000000000000000000000", "8000000000000000000000000000000000000000000000000000000000000000"},
+		// MIN_INT / 1
+		{"0000000000000000000000000000000000000000000000000000000000000001", "8000000000000000000000000000000000000000000000000000000000000000", "8000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	testTwoOperandOp(t, tests, opSdiv)
+}
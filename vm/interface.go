@@ -40,11 +40,17 @@ type StateDB interface {
 	GetCodeSize(common.Address) int
 
 	AddRefund(uint64)
+	SubRefund(uint64)
 	GetRefund() uint64
 
 	GetState(common.Address, common.Hash) common.Hash
 	SetState(common.Address, common.Hash, common.Hash)
 
+	// GetTransientState and SetTransientState access the transient storage
+	// discarded at the end of the transaction, see EIP-1153.
+	GetTransientState(common.Address, common.Hash) common.Hash
+	SetTransientState(common.Address, common.Hash, common.Hash)
+
 	Suicide(common.Address) bool
 	HasSuicided(common.Address) bool
 
@@ -76,3 +82,15 @@ type CallContext interface {
 	// Create a new contract
 	Create(env *EVM, me ContractRef, data []byte, gas, value *big.Int) ([]byte, common.Address, error)
 }
+
+// ViewCache caches results of successful static calls, keyed by the hash of
+// caller and callee address, Context.StateRoot, block number and input.
+type ViewCache interface {
+	Get(key common.Hash) (ret []byte, gasUsed uint64, ok bool)
+	Set(key common.Hash, ret []byte, gasUsed uint64)
+}
+
+// GasOracle suggests gas price for an execution context.
+type GasOracle interface {
+	SuggestGasPrice(ctx Context) *big.Int
+}
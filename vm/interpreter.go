@@ -17,11 +17,16 @@
 package vm
 
 import (
-	"fmt"
+	"math/big"
+	"sort"
 	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // Config are the configuration options for the Interpreter
@@ -30,17 +35,195 @@ type Config struct {
 	Debug bool
 	// Tracer is the op code logger
 	Tracer Tracer
+	// ReadOnly executes everything in static context, so any state
+	// modification fails with write protection error.
+	ReadOnly bool
+	// IntPoolLimit caps the number of scratch integers kept by the interpreter's
+	// intPool, which is always in use. poolLimit is used if zero, and pooling
+	// is disabled if negative.
+	IntPoolLimit int
+	// EnforcePause rejects calls to contracts paused by Context.IsPaused
+	// with ErrContractPaused.
+	EnforcePause bool
 	// NoRecursion disabled Interpreter call, callcode,
 	// delegate call and create.
 	NoRecursion bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// MaxStorageWrites limits the number of SSTORE operations performed
+	// by a transaction. Writes rolled back by a reverted call are not counted.
+	// Zero means unlimited. The count starts from EVM.SetStorageWrites, which
+	// runtime uses to share it across clauses of the transaction.
+	MaxStorageWrites int
+	// MaxLogs limits the number of logs emitted by a transaction. Logs
+	// rolled back by a reverted call are not counted. Zero means unlimited.
+	// The count starts from EVM.SetLogCount, which runtime uses to share
+	// it across clauses of the transaction.
+	MaxLogs int
+	// EIP2200 enables net gas metering of SSTORE, see SstoreGas.
+	EIP2200 bool
+	// CreateGas is the base gas of the CREATE instruction.
+	// params.CreateGas is used if left zero.
+	CreateGas uint64
+	// InitCodeWordGas is the gas charged by CREATE per word of init code
+	// (EIP-3860). Zero by default.
+	InitCodeWordGas uint64
+	// ResetNonceOnFailedCreate restores the caller's nonce if CREATE fails.
+	// The nonce is kept incremented on failure otherwise, as ethereum does.
+	ResetNonceOnFailedCreate bool
+	// AddressDeriver derives address of the contract created by the caller.
+	// It overrides Context.NewContractAddress if set. The standard RLP-nonce
+	// scheme is used if neither is set.
+	AddressDeriver func(caller common.Address, nonce uint64, initCode []byte) common.Address
+	// DetectLivelock enables aborting execution with ErrLivelock, when the same
+	// JUMPDEST is revisited with identical stack and nearly the same gas too many times.
+	DetectLivelock bool
+	// MaxReturnBytes caps the data size of RETURN and REVERT. Exceeding it
+	// aborts execution with ErrReturnTooLarge. Zero means unlimited.
+	MaxReturnBytes uint64
+	// SponsorCheck is consulted before a transaction is executed, if the gas
+	// is paid by an account other than the origin. Returning false aborts the
+	// transaction with ErrSponsorRejected.
+	SponsorCheck func(gasPayer, origin common.Address, gas uint64) bool
+	// MinGasPrice is the minimum gas price accepted by Call and Create,
+	// which fail with ErrGasPriceTooLow otherwise. No minimum if nil.
+	MinGasPrice *big.Int
+	// ZeroAddressHandler handles calls to the zero address made by Call in place
+	// of normal execution, if set. No value is transferred, and all gas is
+	// consumed if it returns an error.
+	ZeroAddressHandler func(input []byte) ([]byte, error)
+	// MaxTransferValue caps the value transferred by Call, which fails with
+	// ErrTransferTooLarge if exceeded. No limit if nil.
+	MaxTransferValue *big.Int
+	// CustomOps registers experimental operations for opcodes not assigned
+	// in the jump table. They're executed in place of the invalid opcode error.
+	CustomOps map[OpCode]CustomOpFunc
+	// OnInvalid is called with the program counter before execution fails
+	// with ErrInvalidOpcode.
+	OnInvalid func(pc uint64)
+	// TrackStack enables recording of the deepest stack size reached, see EVM.MaxStackDepth.
+	TrackStack bool
+	// TrackTouched enables recording of addresses read or written, see runtime.Output.TouchedAddresses.
+	TrackTouched bool
+	// ReapEmpty enables deletion of accounts touched by a clause and left empty, as EIP-161 does,
+	// see runtime.Output.Reaped.
+	ReapEmpty bool
+	// Homestead overrides whether homestead rules of code storage apply to
+	// contract creation, which are decided by the chain config if nil.
+	Homestead *bool
+	// EIP3855 enables the PUSH0 instruction, if nil or true.
+	// It takes no effect if JumpTable is set.
+	EIP3855 *bool
+	// EIP1884 enables the SELFBALANCE instruction.
+	// It takes no effect if JumpTable is set.
+	EIP1884 bool
+	// EIP1052 enables the EXTCODEHASH instruction.
+	// It takes no effect if JumpTable is set.
+	EIP1052 bool
+	// EIP1153 enables the TLOAD and TSTORE instructions.
+	// It takes no effect if JumpTable is set.
+	EIP1153 bool
+	// GasOracle suggests gas price for the context whose GasPrice is unset.
+	// If set, runtime leaves GasPrice unset instead of falling back to the base gas price.
+	GasOracle GasOracle
+	// ViewCache is consulted by top level StaticCall of a ReadOnly EVM not
+	// in Debug, so identical static calls on the same state are executed only once.
+	ViewCache ViewCache
+	// CoverageMap counts executed times of each opcode if not nil.
+	CoverageMap map[OpCode]uint64
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
 	JumpTable [256]operation
 }
 
+// Hash returns a deterministic hash of the config fields affecting execution,
+// for reproducing the execution later. Hooks, tracer and caches are not
+// hashable thus excluded, and custom ops are identified by their opcodes only.
+func (cfg *Config) Hash() common.Hash {
+	// optional values are encoded as empty or single element lists, so unset
+	// is distinguished from zero.
+	optBool := func(b *bool) []bool {
+		if b == nil {
+			return nil
+		}
+		return []bool{*b}
+	}
+	optBig := func(b *big.Int) []*big.Int {
+		if b == nil {
+			return nil
+		}
+		return []*big.Int{b}
+	}
+	customOps := make([]byte, 0, len(cfg.CustomOps))
+	for op := range cfg.CustomOps {
+		customOps = append(customOps, byte(op))
+	}
+	sort.Slice(customOps, func(i, j int) bool { return customOps[i] < customOps[j] })
+
+	data, err := rlp.EncodeToBytes([]interface{}{
+		cfg.ReadOnly,
+		cfg.EnforcePause,
+		cfg.NoRecursion,
+		uint64(cfg.MaxStorageWrites),
+		uint64(cfg.MaxLogs),
+		cfg.EIP2200,
+		cfg.CreateGas,
+		cfg.InitCodeWordGas,
+		cfg.ResetNonceOnFailedCreate,
+		cfg.DetectLivelock,
+		cfg.MaxReturnBytes,
+		optBig(cfg.MinGasPrice),
+		optBig(cfg.MaxTransferValue),
+		customOps,
+		cfg.ReapEmpty,
+		optBool(cfg.Homestead),
+		optBool(cfg.EIP3855),
+		cfg.EIP1884,
+		cfg.EIP1052,
+		cfg.EIP1153,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return crypto.Keccak256Hash(data)
+}
+
+const (
+	// livelockGasBucket is the granularity of gas left when fingerprinting visits.
+	livelockGasBucket uint64 = 1024
+	// livelockThreshold is the number of identical visits treated as livelock.
+	livelockThreshold = 32
+)
+
+// livelockKey is the fingerprint of a JUMPDEST visit.
+type livelockKey struct {
+	pc        uint64
+	gasBucket uint64
+	stack     common.Hash
+}
+
+func newLivelockKey(pc uint64, gas uint64, stack *Stack) livelockKey {
+	hw := sha3.NewKeccak256()
+	for _, item := range stack.data {
+		hw.Write(math.PaddedBigBytes(item, 32))
+	}
+	var h common.Hash
+	hw.Sum(h[:0])
+	return livelockKey{pc, gas / livelockGasBucket, h}
+}
+
+// AggregateCoverage sums up opcode coverage maps into a new one.
+func AggregateCoverage(coverages ...map[OpCode]uint64) map[OpCode]uint64 {
+	total := make(map[OpCode]uint64)
+	for _, coverage := range coverages {
+		for op, n := range coverage {
+			total[op] += n
+		}
+	}
+	return total
+}
+
 // Interpreter is used to run Ethereum based contracts and will utilise the
 // passed environment to query external sources for state information.
 // The Interpreter will run the byte code VM based on the passed
@@ -51,8 +234,9 @@ type Interpreter struct {
 	gasTable params.GasTable
 	intPool  *intPool
 
-	readOnly   bool   // Whether to throw on stateful modifications
-	returnData []byte // Last CALL's return data for subsequent reuse
+	readOnly   bool      // Whether to throw on stateful modifications
+	returnData []byte    // Last CALL's return data for subsequent reuse
+	contract   *Contract // Currently executing contract
 }
 
 // NewInterpreter returns a new instance of the Interpreter.
@@ -71,13 +255,26 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 		default:
 			cfg.JumpTable = frontierInstructionSet
 		}
+		if cfg.EIP3855 == nil || *cfg.EIP3855 {
+			enableEIP3855(&cfg.JumpTable)
+		}
+		if cfg.EIP1884 {
+			enableEIP1884(&cfg.JumpTable)
+		}
+		if cfg.EIP1052 {
+			enableEIP1052(&cfg.JumpTable)
+		}
+		if cfg.EIP1153 {
+			enableEIP1153(&cfg.JumpTable)
+		}
 	}
 
 	return &Interpreter{
 		evm:      evm,
 		cfg:      cfg,
 		gasTable: evm.ChainConfig().GasTable(evm.BlockNumber),
-		intPool:  newIntPool(),
+		intPool:  newIntPool(cfg.IntPoolLimit),
+		readOnly: cfg.ReadOnly,
 	}
 }
 
@@ -90,10 +287,16 @@ func (in *Interpreter) enforceRestrictions(op OpCode, operation operation, stack
 			// account to the others means the state is modified and should also
 			// return with an error.
 			if operation.writes || (op == CALL && stack.Back(2).BitLen() > 0) {
-				return errWriteProtection
+				return ErrWriteProtection
 			}
 		}
 	}
+	// Check the size before memory expansion, so huge return data is never allocated.
+	if max := in.cfg.MaxReturnBytes; max > 0 && (op == RETURN || op == REVERT) {
+		if size := stack.Back(1); size.BitLen() > 64 || size.Uint64() > max {
+			return ErrReturnTooLarge
+		}
+	}
 	return nil
 }
 
@@ -106,7 +309,12 @@ func (in *Interpreter) enforceRestrictions(op OpCode, operation operation, stack
 func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err error) {
 	// Increment the call depth which is restricted to 1024
 	in.evm.depth++
-	defer func() { in.evm.depth-- }()
+	parent := in.contract
+	in.contract = contract
+	defer func() {
+		in.evm.depth--
+		in.contract = parent
+	}()
 
 	// Reset the previous call's return data. It's unimportant to preserve the old buffer
 	// as every returning call will return new data anyway.
@@ -141,9 +349,16 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		pcCopy  uint64 // needed for the deferred Tracer
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
+		visits  map[livelockKey]int
 	)
 	contract.Input = input
 
+	defer func() {
+		if err != nil && err != errExecutionReverted {
+			err = &ExecError{PC: pc, Op: op, Err: err}
+		}
+	}()
+
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -170,7 +385,17 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		op = contract.GetOp(pc)
 		operation := in.cfg.JumpTable[op]
 		if !operation.valid {
-			return nil, fmt.Errorf("invalid opcode 0x%x", int(op))
+			if custom, ok := in.cfg.CustomOps[op]; ok {
+				if err := custom(&OpContext{Contract: contract, Memory: mem, stack: stack}); err != nil {
+					return nil, err
+				}
+				pc++
+				continue
+			}
+			if in.cfg.OnInvalid != nil {
+				in.cfg.OnInvalid(pc)
+			}
+			return nil, ErrInvalidOpcode
 		}
 		if err := operation.validateStack(stack); err != nil {
 			return nil, err
@@ -209,8 +434,25 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 			logged = true
 		}
 
+		if in.cfg.DetectLivelock && op == JUMPDEST {
+			if visits == nil {
+				visits = make(map[livelockKey]int)
+			}
+			key := newLivelockKey(pc, contract.Gas, stack)
+			if visits[key]++; visits[key] >= livelockThreshold {
+				return nil, ErrLivelock
+			}
+		}
+
+		if in.cfg.CoverageMap != nil {
+			in.cfg.CoverageMap[op]++
+		}
+
 		// execute the operation
 		res, err := operation.execute(&pc, in.evm, contract, mem, stack)
+		if in.cfg.TrackStack && stack.len() > in.evm.maxStackDepth {
+			in.evm.maxStackDepth = stack.len()
+		}
 		// verifyPool is a build flag. Pool verification makes sure the integrity
 		// of the integer pool by comparing values to a default value.
 		if verifyPool {
@@ -233,5 +475,5 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 			pc++
 		}
 	}
-	return nil, nil
+	return nil, ErrAborted
 }
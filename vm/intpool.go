@@ -25,11 +25,17 @@ const poolLimit = 256
 // intPool is a pool of big integers that
 // can be reused for all big.Int operations.
 type intPool struct {
-	pool *Stack
+	pool  *Stack
+	limit int
 }
 
-func newIntPool() *intPool {
-	return &intPool{pool: newstack()}
+// newIntPool creates a pool holding up to about limit integers.
+// poolLimit is used if limit is zero, and nothing is pooled if negative.
+func newIntPool(limit int) *intPool {
+	if limit == 0 {
+		limit = poolLimit
+	}
+	return &intPool{pool: newstack(), limit: limit}
 }
 
 // get retrieves a big int from the pool, allocating one if the pool is empty.
@@ -53,7 +59,7 @@ func (p *intPool) getZero() *big.Int {
 // put returns an allocated big int to the pool to be later reused by get calls.
 // Note, the values as saved as is; neither put nor get zeroes the ints out!
 func (p *intPool) put(is ...*big.Int) {
-	if len(p.pool.data) > poolLimit {
+	if p.limit < 0 || len(p.pool.data) > p.limit {
 		return
 	}
 	for _, i := range is {
@@ -57,6 +57,53 @@ var (
 	constantinopleInstructionSet = NewConstantinopleInstructionSet()
 )
 
+// enableEIP3855 adds the PUSH0 instruction to the jump table.
+func enableEIP3855(jt *[256]operation) {
+	jt[PUSH0] = operation{
+		execute:       opPush0,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+}
+
+// enableEIP1884 adds the SELFBALANCE instruction to the jump table.
+func enableEIP1884(jt *[256]operation) {
+	jt[SELFBALANCE] = operation{
+		execute:       opSelfBalance,
+		gasCost:       constGasFunc(GasFastStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+}
+
+// enableEIP1052 adds the EXTCODEHASH instruction to the jump table.
+func enableEIP1052(jt *[256]operation) {
+	jt[EXTCODEHASH] = operation{
+		execute:       opExtCodeHash,
+		gasCost:       constGasFunc(GasExtCodeHash),
+		validateStack: makeStackFunc(1, 1),
+		valid:         true,
+	}
+}
+
+// enableEIP1153 adds the TLOAD and TSTORE instructions to the jump table.
+func enableEIP1153(jt *[256]operation) {
+	jt[TLOAD] = operation{
+		execute:       opTload,
+		gasCost:       constGasFunc(GasTransient),
+		validateStack: makeStackFunc(1, 1),
+		valid:         true,
+	}
+	jt[TSTORE] = operation{
+		execute:       opTstore,
+		gasCost:       constGasFunc(GasTransient),
+		validateStack: makeStackFunc(2, 0),
+		valid:         true,
+		writes:        true,
+	}
+}
+
 // NewConstantinopleInstructionSet returns the frontier, homestead
 // byzantium and contantinople instructions.
 func NewConstantinopleInstructionSet() [256]operation {
@@ -908,7 +955,7 @@ func NewFrontierInstructionSet() [256]operation {
 		},
 		CREATE: {
 			execute:       opCreate,
-			gasCost:       gasCreate,
+			gasCost:       gasCreateOp,
 			validateStack: makeStackFunc(3, 1),
 			memorySize:    memoryCreate,
 			valid:         true,
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -232,6 +233,20 @@ func WriteTrace(writer io.Writer, logs []StructLog) {
 	}
 }
 
+// FormatTrace formats structured logs in a human readable text, one step per line,
+// with pc, opcode, gas, gas cost and depth.
+func FormatTrace(steps []StructLog) string {
+	var buf bytes.Buffer
+	for _, step := range steps {
+		fmt.Fprintf(&buf, "pc=%08d op=%-16s gas=%v cost=%v depth=%d", step.Pc, step.Op, step.Gas, step.GasCost, step.Depth)
+		if step.Err != nil {
+			fmt.Fprintf(&buf, " ERROR: %v", step.Err)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
 // WriteLogs writes vm logs in a readable format to the given writer
 func WriteLogs(writer io.Writer, logs []*types.Log) {
 	for _, log := range logs {
@@ -0,0 +1,31 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// derivableLogs implements types.DerivableList.
+type derivableLogs []*types.Log
+
+func (ls derivableLogs) Len() int { return len(ls) }
+
+func (ls derivableLogs) GetRlp(i int) []byte {
+	data, err := rlp.EncodeToBytes(ls[i])
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// LogsRoot computes the merkle root of the logs, which are keyed by
+// RLP-encoded index and valued by RLP-encoded consensus fields.
+func LogsRoot(logs []*types.Log) common.Hash {
+	return types.DeriveSha(derivableLogs(logs))
+}
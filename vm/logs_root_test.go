@@ -0,0 +1,35 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLogsRoot(t *testing.T) {
+	logs := []*types.Log{
+		{Address: common.HexToAddress("1"), Topics: []common.Hash{common.HexToHash("a")}, Data: []byte{1}},
+		{Address: common.HexToAddress("2"), Data: []byte{2, 3}},
+	}
+	root := LogsRoot(logs)
+	if root != LogsRoot(logs) {
+		t.Errorf("expected stable root")
+	}
+	// non-consensus fields are not part of the root
+	logs[0].BlockNumber = 100
+	if got := LogsRoot(logs); got != root {
+		t.Errorf("expected root %x, got %x", root, got)
+	}
+	if got := LogsRoot(append(logs, &types.Log{Address: common.HexToAddress("3")})); got == root {
+		t.Errorf("expected root to change when a log is added")
+	}
+	if got := LogsRoot(nil); got != types.EmptyRootHash {
+		t.Errorf("expected empty root %x, got %x", types.EmptyRootHash, got)
+	}
+}
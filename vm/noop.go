@@ -56,9 +56,12 @@ func (NoopStateDB) GetCode(common.Address) []byte
 func (NoopStateDB) SetCode(common.Address, []byte)                                     {}
 func (NoopStateDB) GetCodeSize(common.Address) int                                     { return 0 }
 func (NoopStateDB) AddRefund(uint64)                                                   {}
+func (NoopStateDB) SubRefund(uint64)                                                   {}
 func (NoopStateDB) GetRefund() uint64                                                  { return 0 }
 func (NoopStateDB) GetState(common.Address, common.Hash) common.Hash                   { return common.Hash{} }
 func (NoopStateDB) SetState(common.Address, common.Hash, common.Hash)                  {}
+func (NoopStateDB) GetTransientState(common.Address, common.Hash) common.Hash          { return common.Hash{} }
+func (NoopStateDB) SetTransientState(common.Address, common.Hash, common.Hash)         {}
 func (NoopStateDB) Suicide(common.Address) bool                                        { return false }
 func (NoopStateDB) HasSuicided(common.Address) bool                                    { return false }
 func (NoopStateDB) Exist(common.Address) bool                                          { return false }
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 The VeChainThor developers
+
+// Distributed under the GNU Lesser General Public License v3.0 software license, see the accompanying
+// file LICENSE or <https://www.gnu.org/licenses/lgpl-3.0.html>
+
+// Package vmtest provides helpers for end-to-end tests of VM execution.
+package vmtest
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
+)
+
+// SignedCall signs clauses with gas limit by the given private key, to be executed
+// by runtime.Runtime.ExecuteSignedClauses.
+// It returns the address of the key as origin, and the signature.
+func SignedCall(priv *ecdsa.PrivateKey, clauses []*tx.Clause, gas uint64) (origin thor.Address, sig []byte) {
+	builder := new(tx.Builder).Gas(gas)
+	for _, c := range clauses {
+		builder.Clause(c)
+	}
+	sig, err := crypto.Sign(builder.Build().SigningHash().Bytes(), priv)
+	if err != nil {
+		panic(err)
+	}
+	return thor.Address(crypto.PubkeyToAddress(priv.PublicKey)), sig
+}